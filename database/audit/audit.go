@@ -0,0 +1,78 @@
+// Package audit records the changes made to gorm models in an audit table. Auditing is enabled per connection
+// by calling Enable, which registers callbacks for every create, update and delete statement.
+//
+// Fields tagged with `audit:"-"` are excluded from the recorded changes:
+//
+//	type User struct {
+//		gorm.Model
+//		Email    string
+//		Password string `audit:"-"`
+//	}
+package audit
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	// ActionCreate identifies records produced by create statements.
+	ActionCreate = "create"
+
+	// ActionUpdate identifies records produced by update statements.
+	ActionUpdate = "update"
+
+	// ActionDelete identifies records produced by delete statements, including soft deletes.
+	ActionDelete = "delete"
+)
+
+// Record is the gorm model used to persist changes in the audit table.
+type Record struct {
+	ID uint `gorm:"primaryKey"`
+	// EntityType contains the name of the model that changed.
+	EntityType string `gorm:"size:255;not null;index:idx_audit_records_entity"`
+	// EntityID contains the primary key of the entity that changed. Composite primary keys are separated by commas.
+	EntityID string `gorm:"size:255;not null;index:idx_audit_records_entity"`
+	// Action contains the kind of change: ActionCreate, ActionUpdate or ActionDelete.
+	Action string `gorm:"size:16;not null"`
+	// Actor identifies who made the change. It's read from the statement context using ActorFromContext.
+	Actor string `gorm:"size:255"`
+	// Changes contains a JSON object with the changed columns as keys and Change values.
+	Changes   string
+	CreatedAt time.Time
+}
+
+// TableName returns the name of the audit table.
+func (Record) TableName() string {
+	return "audit_records"
+}
+
+// Change contains the value of a column before and after a change. Old is empty when creating entities, and New
+// is empty when deleting them.
+type Change struct {
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// actorKey is the context key used to store the actor.
+type actorKey struct{}
+
+// WithActor returns a copy of ctx holding the given actor. Statements executed with the returned context are
+// attributed to that actor.
+//
+//	db.WithContext(audit.WithActor(ctx, userID)).Create(&order)
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor held by ctx, if any.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok
+}
+
+// Migrate creates or updates the audit table.
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Record{})
+}
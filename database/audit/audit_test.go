@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+)
+
+type User struct {
+	gorm.Model
+	Email    string
+	Active   bool
+	Password string `audit:"-"`
+}
+
+func TestAudit(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
+
+type AuditTestSuite struct {
+	suite.Suite
+
+	db *gorm.DB
+
+	ctx context.Context
+}
+
+func (s *AuditTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "audit_test.db")))
+	s.Require().NoError(err)
+	s.Require().NoError(Migrate(db))
+	s.Require().NoError(db.AutoMigrate(&User{}))
+	s.Require().NoError(Enable(db))
+	s.db = db
+	s.ctx = WithActor(context.Background(), "admin")
+}
+
+func (s *AuditTestSuite) TearDownTest() {
+	sqlDB, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(sqlDB.Close())
+}
+
+func (s *AuditTestSuite) records() []Record {
+	var records []Record
+	s.Require().NoError(s.db.Order("id").Find(&records).Error)
+	return records
+}
+
+func (s *AuditTestSuite) changes(r Record) map[string]Change {
+	var changes map[string]Change
+	s.Require().NoError(json.Unmarshal([]byte(r.Changes), &changes))
+	return changes
+}
+
+func (s *AuditTestSuite) TestCreate() {
+	user := User{Email: "marcos@gojaguar.dev", Password: "secret"}
+	s.Require().NoError(s.db.WithContext(s.ctx).Create(&user).Error)
+
+	records := s.records()
+	s.Require().Len(records, 1)
+	s.Assert().Equal("User", records[0].EntityType)
+	s.Assert().Equal("1", records[0].EntityID)
+	s.Assert().Equal(ActionCreate, records[0].Action)
+	s.Assert().Equal("admin", records[0].Actor)
+
+	changes := s.changes(records[0])
+	s.Assert().Equal("marcos@gojaguar.dev", changes["email"].New)
+	s.Assert().NotContains(changes, "password")
+}
+
+func (s *AuditTestSuite) TestCreate_Bulk() {
+	users := []User{{Email: "a@gojaguar.dev"}, {Email: "b@gojaguar.dev"}}
+	s.Require().NoError(s.db.WithContext(s.ctx).Create(&users).Error)
+
+	records := s.records()
+	s.Require().Len(records, 2)
+	s.Assert().Equal("1", records[0].EntityID)
+	s.Assert().Equal("2", records[1].EntityID)
+}
+
+func (s *AuditTestSuite) TestUpdate() {
+	user := User{Email: "marcos@gojaguar.dev", Active: true, Password: "secret"}
+	s.Require().NoError(s.db.Create(&user).Error)
+
+	s.Require().NoError(s.db.WithContext(s.ctx).Model(&User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"active": false, "password": "changed"}).Error)
+
+	records := s.records()
+	s.Require().Len(records, 2)
+	s.Assert().Equal(ActionUpdate, records[1].Action)
+	s.Assert().Equal("admin", records[1].Actor)
+
+	changes := s.changes(records[1])
+	s.Assert().Equal(Change{Old: true, New: false}, changes["active"])
+	s.Assert().Contains(changes, "updated_at")
+	s.Assert().NotContains(changes, "email")
+	s.Assert().NotContains(changes, "password")
+}
+
+func (s *AuditTestSuite) TestUpdate_ModelPrimaryKey() {
+	user := User{Email: "marcos@gojaguar.dev"}
+	s.Require().NoError(s.db.Create(&user).Error)
+
+	s.Require().NoError(s.db.WithContext(s.ctx).Model(&user).Update("email", "huck@gojaguar.dev").Error)
+
+	records := s.records()
+	s.Require().Len(records, 2)
+	changes := s.changes(records[1])
+	s.Assert().Equal(Change{Old: "marcos@gojaguar.dev", New: "huck@gojaguar.dev"}, changes["email"])
+}
+
+func (s *AuditTestSuite) TestDelete() {
+	user := User{Email: "marcos@gojaguar.dev"}
+	s.Require().NoError(s.db.Create(&user).Error)
+
+	s.Require().NoError(s.db.WithContext(s.ctx).Delete(&User{}, user.ID).Error)
+
+	records := s.records()
+	s.Require().Len(records, 2)
+	s.Assert().Equal(ActionDelete, records[1].Action)
+	s.Assert().Equal("1", records[1].EntityID)
+	s.Assert().Equal("marcos@gojaguar.dev", s.changes(records[1])["email"].Old)
+}
+
+func (s *AuditTestSuite) TestRollback() {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&User{Email: "marcos@gojaguar.dev"}).Error; err != nil {
+			return err
+		}
+		return gorm.ErrInvalidData
+	})
+	s.Assert().Error(err)
+	s.Assert().Empty(s.records())
+}
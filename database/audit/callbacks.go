@@ -0,0 +1,282 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+)
+
+// previousRowsKey is the key used to store the rows read before an update or delete in a gorm.DB instance.
+const previousRowsKey = "jaguar:audit_previous_rows"
+
+// Enable registers the audit callbacks in db. Every create, update and delete statement executed by db writes
+// audit records in the same transaction, the audit table must be created using Migrate beforehand.
+//
+// Statements fail if their audit records cannot be written. Statements executed with gorm.Session's
+// SkipDefaultTransaction enabled may produce changes without audit records if writing them fails.
+func Enable(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("jaguar:audit_after_create", afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("jaguar:audit_before_update", loadPreviousRows); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("jaguar:audit_after_update", afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("jaguar:audit_before_delete", loadPreviousRows); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("jaguar:audit_after_delete", afterDelete)
+}
+
+// afterCreate records the values of the created entities.
+func afterCreate(db *gorm.DB) {
+	if !auditable(db) {
+		return
+	}
+	var records []Record
+	forEachEntity(db.Statement.ReflectValue, func(entity reflect.Value) {
+		changes := make(map[string]Change)
+		for _, field := range auditedFields(db.Statement.Schema) {
+			value, zero := field.ValueOf(db.Statement.Context, entity)
+			if zero {
+				continue
+			}
+			changes[field.DBName] = Change{New: value}
+		}
+		records = append(records, newRecord(db, ActionCreate, entity, changes))
+	})
+	writeRecords(db, records)
+}
+
+// afterUpdate compares the rows read before the update with their current values, and records the changed
+// columns.
+func afterUpdate(db *gorm.DB) {
+	if !auditable(db) {
+		return
+	}
+	previous, ok := previousRows(db)
+	if !ok || previous.Len() == 0 {
+		return
+	}
+
+	current, err := loadRows(db, primaryKeyCondition(db.Statement, previous))
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	currentByID := make(map[string]reflect.Value, current.Len())
+	forEachEntity(current, func(entity reflect.Value) {
+		currentByID[entityID(db, entity)] = entity
+	})
+
+	var records []Record
+	forEachEntity(previous, func(old reflect.Value) {
+		updated, ok := currentByID[entityID(db, old)]
+		if !ok {
+			return
+		}
+		changes := make(map[string]Change)
+		for _, field := range auditedFields(db.Statement.Schema) {
+			oldValue, _ := field.ValueOf(db.Statement.Context, old)
+			newValue, _ := field.ValueOf(db.Statement.Context, updated)
+			if reflect.DeepEqual(oldValue, newValue) {
+				continue
+			}
+			changes[field.DBName] = Change{Old: oldValue, New: newValue}
+		}
+		if len(changes) == 0 {
+			return
+		}
+		records = append(records, newRecord(db, ActionUpdate, old, changes))
+	})
+	writeRecords(db, records)
+}
+
+// afterDelete records the values of the rows read before they were deleted.
+func afterDelete(db *gorm.DB) {
+	if !auditable(db) {
+		return
+	}
+	previous, ok := previousRows(db)
+	if !ok {
+		return
+	}
+	var records []Record
+	forEachEntity(previous, func(entity reflect.Value) {
+		changes := make(map[string]Change)
+		for _, field := range auditedFields(db.Statement.Schema) {
+			value, zero := field.ValueOf(db.Statement.Context, entity)
+			if zero {
+				continue
+			}
+			changes[field.DBName] = Change{Old: value}
+		}
+		records = append(records, newRecord(db, ActionDelete, entity, changes))
+	})
+	writeRecords(db, records)
+}
+
+// loadPreviousRows reads the rows affected by the current statement before they are modified.
+func loadPreviousRows(db *gorm.DB) {
+	if !auditable(db) {
+		return
+	}
+	conditions := statementConditions(db.Statement)
+	if len(conditions) == 0 {
+		// gorm rejects statements without conditions unless global updates are allowed.
+		if !db.AllowGlobalUpdate {
+			return
+		}
+	}
+	rows, err := loadRows(db, conditions...)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	db.InstanceSet(previousRowsKey, rows)
+}
+
+// previousRows returns the rows stored by loadPreviousRows.
+func previousRows(db *gorm.DB) (reflect.Value, bool) {
+	v, ok := db.InstanceGet(previousRowsKey)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	rows, ok := v.(reflect.Value)
+	return rows, ok
+}
+
+// loadRows reads the rows of the current statement's model matching the given conditions, using the same
+// connection as the current statement.
+func loadRows(db *gorm.DB, conditions ...clause.Expression) (reflect.Value, error) {
+	rows := reflect.New(reflect.SliceOf(db.Statement.Schema.ModelType))
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+		Model(reflect.New(db.Statement.Schema.ModelType).Interface())
+	if db.Statement.Unscoped {
+		tx = tx.Unscoped()
+	}
+	if len(conditions) > 0 {
+		tx = tx.Clauses(clause.Where{Exprs: conditions})
+	}
+	if err := tx.Find(rows.Interface()).Error; err != nil {
+		return reflect.Value{}, err
+	}
+	return rows.Elem(), nil
+}
+
+// statementConditions returns the conditions used by the current statement to find the affected rows. It
+// includes the WHERE clause and the primary keys of the statement's model, if they are set.
+func statementConditions(stmt *gorm.Statement) []clause.Expression {
+	var conditions []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			conditions = append(conditions, where.Exprs...)
+		}
+	}
+	if stmt.ReflectValue.IsValid() {
+		if cond := primaryKeyCondition(stmt, stmt.ReflectValue); cond != nil {
+			conditions = append(conditions, cond)
+		}
+	}
+	return conditions
+}
+
+// primaryKeyCondition returns a condition matching the primary keys of the given entity or slice of entities.
+// Entities with a zero primary key are ignored, nil is returned if no entity has a primary key.
+func primaryKeyCondition(stmt *gorm.Statement, value reflect.Value) clause.Expression {
+	var exprs []clause.Expression
+	forEachEntity(value, func(entity reflect.Value) {
+		var and []clause.Expression
+		for _, field := range stmt.Schema.PrimaryFields {
+			v, zero := field.ValueOf(stmt.Context, entity)
+			if zero {
+				return
+			}
+			and = append(and, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: v})
+		}
+		if len(and) > 0 {
+			exprs = append(exprs, clause.And(and...))
+		}
+	})
+	if len(exprs) == 0 {
+		return nil
+	}
+	return clause.Or(exprs...)
+}
+
+// newRecord initializes a new Record for the given entity.
+func newRecord(db *gorm.DB, action string, entity reflect.Value, changes map[string]Change) Record {
+	actor, _ := ActorFromContext(db.Statement.Context)
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		_ = db.AddError(fmt.Errorf("failed to encode audit changes: %w", err))
+	}
+	return Record{
+		EntityType: db.Statement.Schema.Name,
+		EntityID:   entityID(db, entity),
+		Action:     action,
+		Actor:      actor,
+		Changes:    string(encoded),
+	}
+}
+
+// writeRecords writes the given records using the same connection as the current statement.
+func writeRecords(db *gorm.DB, records []Record) {
+	if len(records) == 0 || db.Error != nil {
+		return
+	}
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+	if err := tx.Create(&records).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("failed to write audit records: %w", err))
+	}
+}
+
+// auditable returns true if the current statement should be audited.
+func auditable(db *gorm.DB) bool {
+	stmt := db.Statement
+	return db.Error == nil && stmt.Schema != nil && stmt.Schema.Table != (Record{}).TableName()
+}
+
+// auditedFields returns the fields of the given schema that are stored in the database and not excluded with
+// the `audit:"-"` tag.
+func auditedFields(s *schema.Schema) []*schema.Field {
+	fields := make([]*schema.Field, 0, len(s.Fields))
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.Tag.Get("audit") == "-" {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// entityID returns the primary key of the given entity as a string.
+func entityID(db *gorm.DB, entity reflect.Value) string {
+	values := make([]string, 0, len(db.Statement.Schema.PrimaryFields))
+	for _, field := range db.Statement.Schema.PrimaryFields {
+		v, _ := field.ValueOf(db.Statement.Context, entity)
+		values = append(values, fmt.Sprint(v))
+	}
+	return strings.Join(values, ",")
+}
+
+// forEachEntity calls fn with every struct found in value, which can be a struct, a pointer to a struct or a
+// slice of them.
+func forEachEntity(value reflect.Value, fn func(entity reflect.Value)) {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			forEachEntity(value.Index(i), fn)
+		}
+	case reflect.Struct:
+		fn(value)
+	}
+}
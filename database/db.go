@@ -36,10 +36,6 @@ func dialector(eng string) func(dsn string) gorm.Dialector {
 	case config.EngineSQLite:
 		return sqlite.Open
 	default:
-		return noOp
+		return nil
 	}
 }
-
-func noOp(_ string) gorm.Dialector {
-	return nil
-}
@@ -0,0 +1,128 @@
+package drift
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/gojaguar/jaguar/config"
+	"github.com/gojaguar/jaguar/database"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ExitOK is returned by Run when no drift was detected.
+	ExitOK = 0
+
+	// ExitDrift is returned by Run when drift was detected.
+	ExitDrift = 1
+
+	// ExitError is returned by Run when the drift couldn't be detected.
+	ExitError = 2
+)
+
+// Run implements a command line interface that detects drift between the given models and a database. It
+// parses the given arguments, writes the report to out and returns the exit code, which allows failing CI steps.
+//
+//	Applications expose the command by calling Run from a main package with their models:
+//
+//	func main() {
+//		os.Exit(drift.Run(os.Args[1:], os.Stdout, &User{}, &Order{}))
+//	}
+//
+// Supported flags: -engine, -host, -port, -user, -password, -name, -charset, -env-prefix and -json. Connection
+// settings whose flag isn't set are read from the environment variables named by the env tags of config.Database,
+// such as PASSWORD, prefixed with the value of -env-prefix. The password should be passed through the environment,
+// since flags are visible in process listings and CI logs:
+//
+//	DB_PASSWORD=secret drift -env-prefix DB_ -host localhost -user app -name app
+func Run(args []string, out io.Writer, models ...interface{}) int {
+	var cfg config.Database
+	var asJSON bool
+	var prefix string
+
+	fs := flag.NewFlagSet("drift", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.StringVar(&cfg.Engine, "engine", config.EngineMySQL, "database engine: mysql, postgres or sqlite")
+	fs.StringVar(&cfg.Host, "host", "", "database host")
+	fs.UintVar(&cfg.Port, "port", 3306, "database port")
+	fs.StringVar(&cfg.User, "user", "", "database user")
+	fs.StringVar(&cfg.Password, "password", "", "database password, prefer the PASSWORD environment variable")
+	fs.StringVar(&cfg.Name, "name", "", "database name")
+	fs.StringVar(&cfg.Charset, "charset", "utf8mb4", "database charset")
+	fs.StringVar(&prefix, "env-prefix", "", "prefix of the environment variables holding the connection settings")
+	fs.BoolVar(&asJSON, "json", false, "write the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return ExitError
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if err := fromEnv(&cfg, prefix, set); err != nil {
+		fmt.Fprintln(out, "Failed to read the environment:", err)
+		return ExitError
+	}
+
+	db, err := database.SetupConnectionSQL(cfg)
+	if err != nil {
+		fmt.Fprintln(out, "Failed to connect to database:", err)
+		return ExitError
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	report, err := Detect(db, models...)
+	if err != nil {
+		fmt.Fprintln(out, "Failed to detect schema drift:", err)
+		return ExitError
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintln(out, "Failed to write report:", err)
+			return ExitError
+		}
+	} else {
+		fmt.Fprint(out, report.String())
+	}
+
+	if report.HasDrift() {
+		return ExitDrift
+	}
+	return ExitOK
+}
+
+// fromEnv sets the fields of cfg from the environment variables named by their env tags, prefixed with prefix.
+// Fields whose flag, named after the lowercase field name, is in set are kept.
+func fromEnv(cfg *config.Database, prefix string, set map[string]bool) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag := strings.Split(field.Tag.Get("env"), ",")[0]
+		if tag == "" || set[strings.ToLower(field.Name)] {
+			continue
+		}
+		value, ok := os.LookupEnv(prefix + tag)
+		if !ok {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String:
+			v.Field(i).SetString(value)
+		case reflect.Uint:
+			n, err := strconv.ParseUint(value, 10, 0)
+			if err != nil {
+				return fmt.Errorf("%s%s: %w", prefix, tag, err)
+			}
+			v.Field(i).SetUint(n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Package drift detects differences between gorm models and the schema of a live database. It's used to verify
+// that a database was migrated before deploying a new version of an application.
+package drift
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"sort"
+	"strings"
+)
+
+// Report contains the differences found between a set of models and a database.
+type Report struct {
+	// Tables contains the tables that differ from their model. Tables matching their model are not included.
+	Tables []TableDiff `json:"tables"`
+}
+
+// HasDrift returns true if any difference was found.
+func (r Report) HasDrift() bool {
+	return len(r.Tables) > 0
+}
+
+// String returns a human-readable description of the differences found.
+func (r Report) String() string {
+	if !r.HasDrift() {
+		return "No schema drift detected.\n"
+	}
+	var b strings.Builder
+	for _, t := range r.Tables {
+		fmt.Fprintf(&b, "Table %s (%s):\n", t.Table, t.Model)
+		if t.Missing {
+			b.WriteString("  - table is missing\n")
+			continue
+		}
+		for _, c := range t.MissingColumns {
+			fmt.Fprintf(&b, "  - missing column %s\n", c)
+		}
+		for _, c := range t.ExtraColumns {
+			fmt.Fprintf(&b, "  - extra column %s\n", c)
+		}
+		for _, m := range t.TypeMismatches {
+			fmt.Fprintf(&b, "  - column %s has type %s, expected %s\n", m.Column, m.Actual, m.Expected)
+		}
+		for _, i := range t.MissingIndexes {
+			fmt.Fprintf(&b, "  - missing index %s\n", i)
+		}
+	}
+	return b.String()
+}
+
+// TableDiff contains the differences found between a model and its table.
+type TableDiff struct {
+	// Table contains the name of the table.
+	Table string `json:"table"`
+	// Model contains the name of the model.
+	Model string `json:"model"`
+	// Missing is true if the table doesn't exist. No other differences are reported in that case.
+	Missing bool `json:"missing,omitempty"`
+	// MissingColumns contains the columns defined by the model that don't exist in the table.
+	MissingColumns []string `json:"missing_columns,omitempty"`
+	// ExtraColumns contains the columns of the table that are not defined by the model.
+	ExtraColumns []string `json:"extra_columns,omitempty"`
+	// TypeMismatches contains the columns whose type differs from the one defined by the model.
+	TypeMismatches []TypeMismatch `json:"type_mismatches,omitempty"`
+	// MissingIndexes contains the indexes defined by the model that don't exist in the table.
+	MissingIndexes []string `json:"missing_indexes,omitempty"`
+}
+
+// empty returns true if no differences were found.
+func (t TableDiff) empty() bool {
+	return !t.Missing && len(t.MissingColumns) == 0 && len(t.ExtraColumns) == 0 &&
+		len(t.TypeMismatches) == 0 && len(t.MissingIndexes) == 0
+}
+
+// TypeMismatch describes a column whose type differs from the one defined by its model.
+type TypeMismatch struct {
+	Column   string `json:"column"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Detect compares the given models against the database behind db, and returns a Report with the differences.
+// Type comparisons follow the same rules used by gorm's AutoMigrate, primary key types are not compared.
+func Detect(db *gorm.DB, models ...interface{}) (Report, error) {
+	var report Report
+	migrator := db.Migrator()
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return Report{}, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		diff := TableDiff{
+			Table: stmt.Schema.Table,
+			Model: stmt.Schema.Name,
+		}
+
+		if !migrator.HasTable(model) {
+			diff.Missing = true
+			report.Tables = append(report.Tables, diff)
+			continue
+		}
+
+		columnTypes, err := migrator.ColumnTypes(model)
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to read columns of table %s: %w", diff.Table, err)
+		}
+		compareColumns(db, stmt.Schema, columnTypes, &diff)
+
+		for _, name := range indexNames(stmt.Schema) {
+			if !migrator.HasIndex(model, name) {
+				diff.MissingIndexes = append(diff.MissingIndexes, name)
+			}
+		}
+
+		if !diff.empty() {
+			report.Tables = append(report.Tables, diff)
+		}
+	}
+	return report, nil
+}
+
+// compareColumns compares the fields of the given schema with the given columns, storing the differences in diff.
+func compareColumns(db *gorm.DB, s *schema.Schema, columnTypes []gorm.ColumnType, diff *TableDiff) {
+	columns := make(map[string]gorm.ColumnType, len(columnTypes))
+	for _, c := range columnTypes {
+		columns[strings.ToLower(c.Name())] = c
+	}
+
+	expected := make(map[string]struct{})
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.IgnoreMigration {
+			continue
+		}
+		expected[strings.ToLower(field.DBName)] = struct{}{}
+
+		column, ok := columns[strings.ToLower(field.DBName)]
+		if !ok {
+			diff.MissingColumns = append(diff.MissingColumns, field.DBName)
+			continue
+		}
+		if field.PrimaryKey {
+			continue
+		}
+		expectedType := strings.ToLower(db.Dialector.DataTypeOf(field))
+		actualType := strings.ToLower(column.DatabaseTypeName())
+		if !sameType(db, expectedType, actualType) {
+			diff.TypeMismatches = append(diff.TypeMismatches, TypeMismatch{
+				Column:   field.DBName,
+				Expected: expectedType,
+				Actual:   actualType,
+			})
+		}
+	}
+
+	for _, c := range columnTypes {
+		if _, ok := expected[strings.ToLower(c.Name())]; !ok {
+			diff.ExtraColumns = append(diff.ExtraColumns, c.Name())
+		}
+	}
+	sort.Strings(diff.ExtraColumns)
+}
+
+// sameType returns true if the expected data type matches the actual one, considering the type aliases
+// defined by the database dialect.
+func sameType(db *gorm.DB, expected, actual string) bool {
+	if strings.HasPrefix(expected, actual) {
+		return true
+	}
+	for _, alias := range db.Migrator().GetTypeAliases(actual) {
+		if strings.HasPrefix(expected, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexNames returns the sorted names of the indexes defined by the given schema.
+func indexNames(s *schema.Schema) []string {
+	indexes := s.ParseIndexes()
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,134 @@
+package drift
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gojaguar/jaguar/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+)
+
+type User struct {
+	ID    uint
+	Email string `gorm:"uniqueIndex"`
+	Age   int
+	Name  string
+}
+
+type Order struct {
+	ID     uint
+	UserID uint
+}
+
+func TestDrift(t *testing.T) {
+	suite.Run(t, new(DriftTestSuite))
+}
+
+type DriftTestSuite struct {
+	suite.Suite
+
+	db *gorm.DB
+
+	path string
+}
+
+func (s *DriftTestSuite) SetupTest() {
+	s.path = filepath.Join(s.T().TempDir(), "drift_test")
+	db, err := gorm.Open(sqlite.Open(s.path + ".db"))
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *DriftTestSuite) TearDownTest() {
+	sqlDB, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(sqlDB.Close())
+}
+
+func (s *DriftTestSuite) TestDetect_NoDrift() {
+	s.Require().NoError(s.db.AutoMigrate(&User{}, &Order{}))
+
+	report, err := Detect(s.db, &User{}, &Order{})
+	s.Assert().NoError(err)
+	s.Assert().False(report.HasDrift())
+	s.Assert().Equal("No schema drift detected.\n", report.String())
+}
+
+func (s *DriftTestSuite) TestDetect_Drift() {
+	s.Require().NoError(s.db.Exec("CREATE TABLE users (id integer PRIMARY KEY, email text, age text, nickname text)").Error)
+
+	report, err := Detect(s.db, &User{}, &Order{})
+	s.Assert().NoError(err)
+	s.Require().True(report.HasDrift())
+	s.Require().Len(report.Tables, 2)
+
+	users := report.Tables[0]
+	s.Assert().Equal("users", users.Table)
+	s.Assert().Equal("User", users.Model)
+	s.Assert().False(users.Missing)
+	s.Assert().Equal([]string{"name"}, users.MissingColumns)
+	s.Assert().Equal([]string{"nickname"}, users.ExtraColumns)
+	s.Assert().Equal([]TypeMismatch{{Column: "age", Expected: "integer", Actual: "text"}}, users.TypeMismatches)
+	s.Assert().Equal([]string{"idx_users_email"}, users.MissingIndexes)
+
+	orders := report.Tables[1]
+	s.Assert().Equal("orders", orders.Table)
+	s.Assert().True(orders.Missing)
+
+	s.Assert().Contains(report.String(), "missing column name")
+}
+
+func (s *DriftTestSuite) TestRun() {
+	s.Require().NoError(s.db.AutoMigrate(&User{}))
+
+	var out bytes.Buffer
+	code := Run([]string{"-engine", "sqlite", "-name", s.path}, &out, &User{})
+	s.Assert().Equal(ExitOK, code)
+
+	out.Reset()
+	code = Run([]string{"-engine", "sqlite", "-name", s.path, "-json"}, &out, &User{}, &Order{})
+	s.Assert().Equal(ExitDrift, code)
+
+	var report Report
+	s.Require().NoError(json.Unmarshal(out.Bytes(), &report))
+	s.Assert().Len(report.Tables, 1)
+	s.Assert().True(report.Tables[0].Missing)
+
+	code = Run([]string{"-engine", "oracle"}, &out, &User{})
+	s.Assert().Equal(ExitError, code)
+}
+
+func (s *DriftTestSuite) TestRun_Env() {
+	s.Require().NoError(s.db.AutoMigrate(&User{}))
+	s.T().Setenv("DRIFT_ENGINE", "sqlite")
+	s.T().Setenv("DRIFT_NAME", s.path)
+
+	var out bytes.Buffer
+	code := Run([]string{"-env-prefix", "DRIFT_"}, &out, &User{})
+	s.Assert().Equal(ExitOK, code, out.String())
+
+	code = Run([]string{"-env-prefix", "DRIFT_", "-engine", "oracle"}, &out, &User{})
+	s.Assert().Equal(ExitError, code)
+
+	s.T().Setenv("DRIFT_PORT", "invalid")
+	out.Reset()
+	code = Run([]string{"-env-prefix", "DRIFT_"}, &out, &User{})
+	s.Assert().Equal(ExitError, code)
+	s.Assert().Contains(out.String(), "DRIFT_PORT")
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "secret")
+	t.Setenv("DB_USER", "app")
+	t.Setenv("DB_PORT", "5432")
+
+	cfg := config.Database{User: "admin", Port: 3306}
+	assert.NoError(t, fromEnv(&cfg, "DB_", map[string]bool{"user": true}))
+	assert.Equal(t, "secret", cfg.Password)
+	assert.Equal(t, "admin", cfg.User)
+	assert.Equal(t, uint(5432), cfg.Port)
+}
@@ -0,0 +1,159 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Elector uses a Locker to elect a single leader among the processes campaigning for the same key.
+//
+//	e := lock.NewElector(locker, "jobs.cleanup", lock.OnLeadershipChange(func(leader bool) {
+//		log.Println("Leadership changed:", leader)
+//	}))
+//	go e.Run(ctx)
+//
+//	if e.IsLeader() {
+//		runCleanup()
+//	}
+type Elector struct {
+	locker   Locker
+	key      string
+	interval time.Duration
+	timeout  time.Duration
+	ttl      time.Duration
+	onChange func(leader bool)
+	now      func() time.Time
+	leader   atomic.Bool
+
+	// renewed holds when the last successful attempt to acquire or refresh the lock started.
+	renewed time.Time
+	// unreleased is true if releasing the lock failed, so it must be released before campaigning again.
+	unreleased bool
+}
+
+// ElectorOption configures an Elector.
+type ElectorOption func(e *Elector)
+
+// WithRenewInterval sets the time between campaign attempts and lock refreshes. It must be lower than the TTL
+// set with WithLeaseTTL. Defaults to 10 seconds.
+func WithRenewInterval(interval time.Duration) ElectorOption {
+	return func(e *Elector) {
+		e.interval = interval
+	}
+}
+
+// WithLockTimeout sets how long every lock operation can take. Defaults to 5 seconds.
+func WithLockTimeout(timeout time.Duration) ElectorOption {
+	return func(e *Elector) {
+		e.timeout = timeout
+	}
+}
+
+// WithLeaseTTL sets how long the lock stays held after it's acquired or refreshed, which must match the TTL of
+// lease-based lockers. Failed refreshes are retried on the next interval as long as the lock wouldn't expire
+// before then, otherwise the leadership is released. Defaults to 30 seconds.
+func WithLeaseTTL(ttl time.Duration) ElectorOption {
+	return func(e *Elector) {
+		e.ttl = ttl
+	}
+}
+
+// OnLeadershipChange sets the function called every time the current process gains or loses leadership.
+func OnLeadershipChange(fn func(leader bool)) ElectorOption {
+	return func(e *Elector) {
+		e.onChange = fn
+	}
+}
+
+// NewElector initializes a new Elector campaigning for the given key.
+func NewElector(locker Locker, key string, opts ...ElectorOption) *Elector {
+	e := &Elector{
+		locker:   locker,
+		key:      key,
+		interval: 10 * time.Second,
+		timeout:  5 * time.Second,
+		ttl:      30 * time.Second,
+		onChange: func(bool) {},
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// IsLeader returns true if the current process holds the leadership.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns for leadership until the given context is done. While leading, the lock is refreshed on every
+// interval. Leadership is released before returning.
+func (e *Elector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.campaign()
+		select {
+		case <-ctx.Done():
+			e.resign()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign attempts to acquire the leadership, or to keep it if it's already held. Lock operations use their own
+// timeout so canceling Run never interrupts them halfway.
+func (e *Elector) campaign() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	start := e.now()
+	if e.IsLeader() {
+		err := e.locker.Refresh(ctx, e.key)
+		switch {
+		case err == nil:
+			e.renewed = start
+		case errors.Is(err, ErrNotHeld) || e.now().Add(e.interval).Sub(e.renewed) >= e.ttl:
+			e.resign()
+		}
+		return
+	}
+	if e.unreleased && !e.release(ctx) {
+		return
+	}
+	ok, err := e.locker.TryAcquire(ctx, e.key)
+	if err == nil && ok {
+		e.renewed = start
+		e.setLeader(true)
+	}
+}
+
+// resign releases the leadership if it's held. The lock is released even if it was lost, so the Locker forgets
+// about it.
+func (e *Elector) resign() {
+	if !e.IsLeader() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	e.release(ctx)
+	e.setLeader(false)
+}
+
+// release releases the lock, returning false if it failed for a reason other than the lock not being held, in
+// which case it's retried by the next campaign.
+func (e *Elector) release(ctx context.Context) bool {
+	err := e.locker.Release(ctx, e.key)
+	e.unreleased = err != nil && !errors.Is(err, ErrNotHeld)
+	return !e.unreleased
+}
+
+// setLeader updates the leadership status, calling the change handler if it changed.
+func (e *Elector) setLeader(leader bool) {
+	if e.leader.Swap(leader) != leader {
+		e.onChange(leader)
+	}
+}
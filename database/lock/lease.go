@@ -0,0 +1,133 @@
+package lock
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+// Lease is the gorm model used to persist leases in the lease table.
+type Lease struct {
+	// Name contains the key that identifies the lock.
+	Name string `gorm:"primaryKey;size:255"`
+	// Owner identifies the Locker holding the lock.
+	Owner string `gorm:"size:255;not null"`
+	// ExpiresAt determines when the lock can be acquired by another Locker if it's not refreshed.
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+// TableName returns the name of the lease table.
+func (Lease) TableName() string {
+	return "lock_leases"
+}
+
+// Migrate creates or updates the lease table.
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Lease{})
+}
+
+// lease implements Locker using a table where every row represents a lock held until it expires.
+type lease struct {
+	db      *gorm.DB
+	options options
+	now     func() time.Time
+
+	mu   sync.Mutex
+	held map[string]struct{}
+}
+
+// NewLease initializes a Locker using the lease table, which must be created using Migrate beforehand. Locks
+// must be refreshed before the TTL expires, otherwise they can be acquired by other lockers.
+func NewLease(db *gorm.DB, opts ...Option) Locker {
+	return &lease{
+		db:      db,
+		options: newOptions(opts),
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+		held: make(map[string]struct{}),
+	}
+}
+
+// Acquire blocks until the lock identified by key is acquired or the given context is done.
+func (l *lease) Acquire(ctx context.Context, key string) error {
+	return acquire(ctx, l.options.pollInterval, key, l.TryAcquire)
+}
+
+// TryAcquire attempts to acquire the lock identified by key without blocking. Expired leases are taken over.
+func (l *lease) TryAcquire(ctx context.Context, key string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.held[key]; ok {
+		return false, nil
+	}
+
+	now := l.now()
+	row := Lease{Name: key, Owner: l.options.owner, ExpiresAt: now.Add(l.options.ttl)}
+	var acquired bool
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			acquired = true
+			return nil
+		}
+
+		result = tx.Model(&Lease{}).
+			Where("name = ? AND expires_at < ?", key, now).
+			Updates(map[string]interface{}{"owner": row.Owner, "expires_at": row.ExpiresAt})
+		acquired = result.RowsAffected == 1
+		return result.Error
+	})
+	if err != nil || !acquired {
+		return false, err
+	}
+	l.held[key] = struct{}{}
+	return true, nil
+}
+
+// Release releases the lock identified by key. The lock is kept if the lease cannot be deleted, so releasing it
+// can be retried.
+func (l *lease) Release(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.held[key]; !ok {
+		return ErrNotHeld
+	}
+
+	result := l.db.WithContext(ctx).Where("name = ? AND owner = ?", key, l.options.owner).Delete(&Lease{})
+	if result.Error != nil {
+		return result.Error
+	}
+	delete(l.held, key)
+	if result.RowsAffected == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Refresh extends the lease of the lock identified by key by the configured TTL.
+func (l *lease) Refresh(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.held[key]; !ok {
+		return ErrNotHeld
+	}
+
+	now := l.now()
+	result := l.db.WithContext(ctx).Model(&Lease{}).
+		Where("name = ? AND owner = ? AND expires_at >= ?", key, l.options.owner, now).
+		Update("expires_at", now.Add(l.options.ttl))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		delete(l.held, key)
+		return ErrNotHeld
+	}
+	return nil
+}
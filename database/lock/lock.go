@@ -0,0 +1,127 @@
+// Package lock implements distributed locks on top of a database. Locks use Postgres advisory locks and MySQL
+// named locks when available, and fall back to a lease table that works on any database supported by gorm.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	// ErrNotHeld is returned when releasing or refreshing a lock that isn't held by the current Locker.
+	ErrNotHeld = errors.New("lock is not held")
+)
+
+// Locker acquires and releases named locks shared by every process connected to the same database.
+//
+// A lock acquired by a Locker can only be released or refreshed by that same Locker. Acquiring a lock that is
+// already held by the current Locker behaves as if it was held by another process.
+type Locker interface {
+	// Acquire blocks until the lock identified by key is acquired or the given context is done.
+	Acquire(ctx context.Context, key string) error
+	// TryAcquire attempts to acquire the lock identified by key without blocking. It returns true if the lock
+	// was acquired.
+	TryAcquire(ctx context.Context, key string) (bool, error)
+	// Release releases the lock identified by key. It returns ErrNotHeld if the lock isn't held.
+	Release(ctx context.Context, key string) error
+	// Refresh checks that the lock identified by key is still held, extending it if it has an expiration.
+	// It returns ErrNotHeld if the lock was lost.
+	Refresh(ctx context.Context, key string) error
+}
+
+// options contains the configuration shared by every Locker implementation.
+type options struct {
+	ttl          time.Duration
+	pollInterval time.Duration
+	owner        string
+}
+
+// Option configures a Locker.
+type Option func(o *options)
+
+// WithTTL sets how long a lease is valid before it must be refreshed. It's only used by lease-based lockers.
+// Defaults to 30 seconds.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithPollInterval sets the time between attempts when calling Locker.Acquire. Defaults to 500 milliseconds.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+	}
+}
+
+// WithOwner sets the identifier used to mark leases as owned by the current Locker. It's only used by
+// lease-based lockers. Defaults to a random identifier.
+func WithOwner(owner string) Option {
+	return func(o *options) {
+		o.owner = owner
+	}
+}
+
+// newOptions returns the default options with the given opts applied.
+func newOptions(opts []Option) options {
+	o := options{
+		ttl:          30 * time.Second,
+		pollInterval: 500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.owner == "" {
+		o.owner = randomOwner()
+	}
+	return o
+}
+
+// New initializes a Locker for the database behind db. It uses advisory locks on Postgres, named locks on MySQL
+// and a lease table otherwise. The lease table must be created using Migrate beforehand.
+func New(db *gorm.DB, opts ...Option) (Locker, error) {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return NewPostgres(db, opts...)
+	case "mysql":
+		return NewMySQL(db, opts...)
+	default:
+		return NewLease(db, opts...), nil
+	}
+}
+
+// acquire calls tryAcquire until the lock is acquired or the given context is done.
+func acquire(ctx context.Context, interval time.Duration, key string, tryAcquire func(ctx context.Context, key string) (bool, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ok, err := tryAcquire(ctx, key)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// randomOwner returns a random identifier.
+func randomOwner() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().String()
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,236 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLease(t *testing.T) {
+	suite.Run(t, new(LeaseTestSuite))
+}
+
+type LeaseTestSuite struct {
+	suite.Suite
+
+	db *gorm.DB
+}
+
+func (s *LeaseTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "lock_test.db")))
+	s.Require().NoError(err)
+	s.Require().NoError(Migrate(db))
+	s.db = db
+}
+
+func (s *LeaseTestSuite) TearDownTest() {
+	sqlDB, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(sqlDB.Close())
+}
+
+func (s *LeaseTestSuite) TestNew() {
+	locker, err := New(s.db)
+	s.Assert().NoError(err)
+	s.Assert().IsType(&lease{}, locker)
+}
+
+func (s *LeaseTestSuite) TestTryAcquire() {
+	ctx := context.Background()
+	a := NewLease(s.db)
+	b := NewLease(s.db)
+
+	ok, err := a.TryAcquire(ctx, "jobs")
+	s.Assert().NoError(err)
+	s.Assert().True(ok)
+
+	ok, err = b.TryAcquire(ctx, "jobs")
+	s.Assert().NoError(err)
+	s.Assert().False(ok)
+
+	// The lock is not reentrant.
+	ok, err = a.TryAcquire(ctx, "jobs")
+	s.Assert().NoError(err)
+	s.Assert().False(ok)
+
+	s.Assert().ErrorIs(b.Release(ctx, "jobs"), ErrNotHeld)
+	s.Assert().NoError(a.Release(ctx, "jobs"))
+
+	ok, err = b.TryAcquire(ctx, "jobs")
+	s.Assert().NoError(err)
+	s.Assert().True(ok)
+}
+
+func (s *LeaseTestSuite) TestRelease_Failed() {
+	ctx := context.Background()
+	a := NewLease(s.db)
+	s.Require().NoError(a.Acquire(ctx, "jobs"))
+
+	errFailed := errors.New("connection reset")
+	s.Require().NoError(s.db.Callback().Delete().Before("gorm:delete").Register("test:fail", func(db *gorm.DB) {
+		_ = db.AddError(errFailed)
+	}))
+	s.Assert().ErrorIs(a.Release(ctx, "jobs"), errFailed)
+	s.Require().NoError(s.db.Callback().Delete().Remove("test:fail"))
+
+	s.Assert().NoError(a.Refresh(ctx, "jobs"))
+	s.Assert().NoError(a.Release(ctx, "jobs"))
+	s.Assert().ErrorIs(a.Release(ctx, "jobs"), ErrNotHeld)
+}
+
+func (s *LeaseTestSuite) TestAcquire_Expired() {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	a := NewLease(s.db, WithTTL(time.Minute)).(*lease)
+	a.now = func() time.Time { return now }
+	b := NewLease(s.db, WithTTL(time.Minute)).(*lease)
+	b.now = func() time.Time { return now.Add(2 * time.Minute) }
+
+	s.Require().NoError(a.Acquire(ctx, "jobs"))
+	s.Require().NoError(b.Acquire(ctx, "jobs"))
+
+	s.Assert().ErrorIs(a.Refresh(ctx, "jobs"), ErrNotHeld)
+	s.Assert().NoError(b.Refresh(ctx, "jobs"))
+}
+
+func (s *LeaseTestSuite) TestAcquire_ContextDone() {
+	a := NewLease(s.db)
+	b := NewLease(s.db, WithPollInterval(time.Millisecond))
+	s.Require().NoError(a.Acquire(context.Background(), "jobs"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s.Assert().ErrorIs(b.Acquire(ctx, "jobs"), context.DeadlineExceeded)
+}
+
+func (s *LeaseTestSuite) TestElector() {
+	changes := make(map[string][]bool)
+	elector := func(name string) *Elector {
+		return NewElector(NewLease(s.db), "leader", OnLeadershipChange(func(leader bool) {
+			changes[name] = append(changes[name], leader)
+		}))
+	}
+	a, b := elector("a"), elector("b")
+
+	a.campaign()
+	b.campaign()
+	s.Assert().True(a.IsLeader())
+	s.Assert().False(b.IsLeader())
+
+	a.campaign()
+	b.campaign()
+	s.Assert().True(a.IsLeader())
+	s.Assert().False(b.IsLeader())
+
+	a.resign()
+	b.campaign()
+	s.Assert().False(a.IsLeader())
+	s.Assert().True(b.IsLeader())
+
+	b.resign()
+	s.Assert().Equal([]bool{true, false}, changes["a"])
+	s.Assert().Equal([]bool{true, false}, changes["b"])
+}
+
+func (s *LeaseTestSuite) TestElector_RefreshFailed() {
+	locker := &failingLocker{Locker: NewLease(s.db)}
+	var changes []bool
+	e := NewElector(locker, "leader", WithRenewInterval(time.Second), WithLeaseTTL(3*time.Second),
+		OnLeadershipChange(func(leader bool) {
+			changes = append(changes, leader)
+		}),
+	)
+	now := time.Now()
+	e.now = func() time.Time { return now }
+
+	e.campaign()
+	s.Require().True(e.IsLeader())
+
+	// Transient failures are tolerated while the lease wouldn't expire before the next refresh.
+	locker.refresh = errors.New("connection reset")
+	now = now.Add(time.Second)
+	e.campaign()
+	s.Assert().True(e.IsLeader())
+	now = now.Add(time.Second)
+	e.campaign()
+	s.Assert().False(e.IsLeader())
+
+	locker.refresh = nil
+	e.campaign()
+	s.Assert().True(e.IsLeader())
+	locker.refresh = ErrNotHeld
+	e.campaign()
+	s.Assert().False(e.IsLeader())
+	s.Assert().Equal([]bool{true, false, true, false}, changes)
+}
+
+func (s *LeaseTestSuite) TestElector_ReleaseFailed() {
+	locker := &failingLocker{Locker: NewLease(s.db), release: errors.New("connection reset")}
+	e := NewElector(locker, "leader")
+	e.campaign()
+	s.Require().True(e.IsLeader())
+	e.resign()
+	s.Assert().False(e.IsLeader())
+
+	// The release is retried before campaigning again.
+	e.campaign()
+	s.Assert().False(e.IsLeader())
+	locker.release = nil
+	e.campaign()
+	s.Assert().True(e.IsLeader())
+}
+
+func (s *LeaseTestSuite) TestElector_Run() {
+	var mu sync.Mutex
+	var changes []bool
+	e := NewElector(NewLease(s.db), "leader",
+		WithRenewInterval(10*time.Millisecond),
+		OnLeadershipChange(func(leader bool) {
+			mu.Lock()
+			changes = append(changes, leader)
+			mu.Unlock()
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- e.Run(ctx)
+	}()
+	s.Require().Eventually(e.IsLeader, time.Second, time.Millisecond)
+
+	cancel()
+	s.Assert().NoError(<-done)
+	s.Assert().False(e.IsLeader())
+
+	mu.Lock()
+	defer mu.Unlock()
+	s.Assert().Equal([]bool{true, false}, changes)
+}
+
+// failingLocker is a Locker whose refreshes and releases fail with the given errors.
+type failingLocker struct {
+	Locker
+	refresh error
+	release error
+}
+
+func (l *failingLocker) Refresh(ctx context.Context, key string) error {
+	if l.refresh != nil {
+		return l.refresh
+	}
+	return l.Locker.Refresh(ctx, key)
+}
+
+func (l *failingLocker) Release(ctx context.Context, key string) error {
+	if l.release != nil {
+		return l.release
+	}
+	return l.Locker.Release(ctx, key)
+}
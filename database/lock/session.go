@@ -0,0 +1,144 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+	"hash/fnv"
+	"sync"
+)
+
+// session implements Locker using locks bound to a database session, such as Postgres advisory locks or MySQL
+// named locks. Every held lock pins a connection from the pool until it's released.
+type session struct {
+	db      *sql.DB
+	options options
+	lock    func(ctx context.Context, conn *sql.Conn, key string) (bool, error)
+	unlock  func(ctx context.Context, conn *sql.Conn, key string) error
+
+	mu    sync.Mutex
+	conns map[string]*sql.Conn
+}
+
+// NewPostgres initializes a Locker using Postgres advisory locks. Keys are hashed into the 64-bit integers used
+// to identify advisory locks.
+func NewPostgres(db *gorm.DB, opts ...Option) (Locker, error) {
+	return newSession(db, opts, func(ctx context.Context, conn *sql.Conn, key string) (bool, error) {
+		var ok bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", hashKey(key)).Scan(&ok)
+		return ok, err
+	}, func(ctx context.Context, conn *sql.Conn, key string) error {
+		var ok bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", hashKey(key)).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotHeld
+		}
+		return nil
+	})
+}
+
+// NewMySQL initializes a Locker using MySQL named locks. Keys longer than 64 characters are not supported by MySQL.
+func NewMySQL(db *gorm.DB, opts ...Option) (Locker, error) {
+	return newSession(db, opts, func(ctx context.Context, conn *sql.Conn, key string) (bool, error) {
+		var result sql.NullInt64
+		err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", key).Scan(&result)
+		return result.Valid && result.Int64 == 1, err
+	}, func(ctx context.Context, conn *sql.Conn, key string) error {
+		var result sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", key).Scan(&result); err != nil {
+			return err
+		}
+		if !result.Valid || result.Int64 != 1 {
+			return ErrNotHeld
+		}
+		return nil
+	})
+}
+
+// newSession initializes a new session Locker.
+func newSession(
+	db *gorm.DB,
+	opts []Option,
+	lock func(ctx context.Context, conn *sql.Conn, key string) (bool, error),
+	unlock func(ctx context.Context, conn *sql.Conn, key string) error,
+) (Locker, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return &session{
+		db:      sqlDB,
+		options: newOptions(opts),
+		lock:    lock,
+		unlock:  unlock,
+		conns:   make(map[string]*sql.Conn),
+	}, nil
+}
+
+// Acquire blocks until the lock identified by key is acquired or the given context is done.
+func (s *session) Acquire(ctx context.Context, key string) error {
+	return acquire(ctx, s.options.pollInterval, key, s.TryAcquire)
+}
+
+// TryAcquire attempts to acquire the lock identified by key without blocking.
+func (s *session) TryAcquire(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.conns[key]; ok {
+		return false, nil
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	ok, err := s.lock(ctx, conn, key)
+	if err != nil || !ok {
+		_ = conn.Close()
+		return false, err
+	}
+	s.conns[key] = conn
+	return true, nil
+}
+
+// Release releases the lock identified by key and returns its connection to the pool.
+func (s *session) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn, ok := s.conns[key]
+	if !ok {
+		return ErrNotHeld
+	}
+	delete(s.conns, key)
+	err := s.unlock(ctx, conn, key)
+	if closeErr := conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Refresh checks that the connection holding the lock identified by key is still alive. Session locks are
+// released by the database when their connection is lost.
+func (s *session) Refresh(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn, ok := s.conns[key]
+	if !ok {
+		return ErrNotHeld
+	}
+	if err := conn.PingContext(ctx); err != nil {
+		delete(s.conns, key)
+		_ = conn.Close()
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// hashKey converts the given key to the 64-bit integer used to identify a Postgres advisory lock.
+func hashKey(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
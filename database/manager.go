@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gojaguar/jaguar/config"
+	"gorm.io/gorm"
+	"sync"
+)
+
+var (
+	// ErrConnectionNotFound is returned when a connection that hasn't been registered or opened is requested from
+	// a Manager.
+	ErrConnectionNotFound = errors.New("connection not found")
+
+	// ErrConnectionRegistered is returned when registering a connection with a name that is already in use.
+	ErrConnectionRegistered = errors.New("connection already registered")
+)
+
+// Manager holds a set of named database connections and manages their lifecycle. Connections are opened in the
+// order they were registered, and closed in that same order.
+//
+//	Developers use a Manager to handle multiple databases in a single application:
+//
+//	m := database.NewManager()
+//	_ = m.Register("users", cfg.UserDB)
+//	_ = m.Register("data", cfg.DataDB)
+//	if err := m.Open(ctx); err != nil {
+//		return err
+//	}
+//	users, err := m.Get("users")
+//
+// The Manager.Close method can be hooked into a server.Server using server.Builder's OnShutdown method.
+type Manager struct {
+	mu          sync.RWMutex
+	names       []string
+	configs     map[string]config.Database
+	connections map[string]*gorm.DB
+}
+
+// NewManager initializes a new Manager without connections.
+func NewManager() *Manager {
+	return &Manager{
+		configs:     make(map[string]config.Database),
+		connections: make(map[string]*gorm.DB),
+	}
+}
+
+// Register adds the given config.Database under the given name. Registered connections are opened when calling
+// Open. It returns ErrConnectionRegistered if the name is already in use.
+func (m *Manager) Register(name string, cfg config.Database) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.configs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrConnectionRegistered, name)
+	}
+	m.names = append(m.names, name)
+	m.configs[name] = cfg
+	return nil
+}
+
+// Open opens every registered connection that isn't open yet and pings them. If any connection fails to be
+// opened, the connections opened by this call are closed and the error is returned.
+func (m *Manager) Open(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var opened []string
+	for _, name := range m.names {
+		if _, ok := m.connections[name]; ok {
+			continue
+		}
+		db, err := SetupConnectionSQL(m.configs[name])
+		if err == nil {
+			err = ping(ctx, db)
+		}
+		if err != nil {
+			if db != nil {
+				_ = closeConnection(db)
+			}
+			for _, n := range opened {
+				_ = closeConnection(m.connections[n])
+				delete(m.connections, n)
+			}
+			return fmt.Errorf("failed to open connection %s: %w", name, err)
+		}
+		m.connections[name] = db
+		opened = append(opened, name)
+	}
+	return nil
+}
+
+// Get returns the open connection identified by the given name. It returns ErrConnectionNotFound if the
+// connection wasn't registered or hasn't been opened.
+func (m *Manager) Get(name string) (*gorm.DB, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	db, ok := m.connections[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrConnectionNotFound, name)
+	}
+	return db, nil
+}
+
+// Ping pings every open connection, in order. It returns the first error found.
+func (m *Manager) Ping(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, name := range m.names {
+		db, ok := m.connections[name]
+		if !ok {
+			continue
+		}
+		if err := ping(ctx, db); err != nil {
+			return fmt.Errorf("failed to ping connection %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Close closes every open connection in the order they were registered. All the connections are closed even if
+// one of them fails, the first error found is returned.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result error
+	for _, name := range m.names {
+		db, ok := m.connections[name]
+		if !ok {
+			continue
+		}
+		delete(m.connections, name)
+		if err := closeConnection(db); err != nil && result == nil {
+			result = fmt.Errorf("failed to close connection %s: %w", name, err)
+		}
+	}
+	return result
+}
+
+// Shutdown closes every open connection. It has the signature expected by server.Builder's OnShutdown method.
+func (m *Manager) Shutdown(_ context.Context) error {
+	return m.Close()
+}
+
+// ping pings the database behind the given gorm.DB.
+func ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// closeConnection closes the connection pool behind the given gorm.DB.
+func closeConnection(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
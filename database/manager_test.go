@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"github.com/gojaguar/jaguar/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestManager_OpenGetClose(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager()
+	require.NoError(t, m.Register("users", config.Database{Engine: config.EngineSQLite, Name: filepath.Join(dir, "users")}))
+	require.NoError(t, m.Register("data", config.Database{Engine: config.EngineSQLite, Name: filepath.Join(dir, "data")}))
+
+	_, err := m.Get("users")
+	assert.ErrorIs(t, err, ErrConnectionNotFound)
+
+	require.NoError(t, m.Open(context.Background()))
+	assert.NoError(t, m.Ping(context.Background()))
+
+	db, err := m.Get("users")
+	assert.NoError(t, err)
+	assert.NotNil(t, db)
+
+	_, err = m.Get("unknown")
+	assert.ErrorIs(t, err, ErrConnectionNotFound)
+
+	assert.NoError(t, m.Shutdown(context.Background()))
+
+	_, err = m.Get("users")
+	assert.ErrorIs(t, err, ErrConnectionNotFound)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	assert.Error(t, sqlDB.Ping())
+}
+
+func TestManager_RegisterDuplicated(t *testing.T) {
+	m := NewManager()
+	cfg := config.Database{Engine: config.EngineSQLite, Name: filepath.Join(t.TempDir(), "users")}
+	assert.NoError(t, m.Register("users", cfg))
+	assert.ErrorIs(t, m.Register("users", cfg), ErrConnectionRegistered)
+}
+
+func TestManager_OpenInvalidDialect(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager()
+	require.NoError(t, m.Register("users", config.Database{Engine: config.EngineSQLite, Name: filepath.Join(dir, "users")}))
+	require.NoError(t, m.Register("invalid", config.Database{Engine: "oracle"}))
+
+	err := m.Open(context.Background())
+	assert.ErrorIs(t, err, ErrInvalidDialect)
+
+	// Connections opened before the failure should be closed.
+	_, err = m.Get("users")
+	assert.ErrorIs(t, err, ErrConnectionNotFound)
+}
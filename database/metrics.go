@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/gojaguar/jaguar/telemetry"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// metricsStartKey is the key used to store the time a statement started in a gorm.DB instance.
+const metricsStartKey = "jaguar:metrics_start"
+
+// Metrics exports connection pool statistics and query latencies of database connections to a telemetry.Registry.
+// Every metric is labelled by connection name, allowing a single Metrics to observe multiple connections.
+//
+//	reg := telemetry.NewRegistry()
+//	m := database.NewMetrics(reg)
+//	if err := m.Instrument("users", db); err != nil {
+//		return err
+//	}
+//	go m.WatchStats(ctx, "users", db, 15*time.Second)
+type Metrics struct {
+	openConnections  *telemetry.Gauge
+	inUseConnections *telemetry.Gauge
+	idleConnections  *telemetry.Gauge
+	waitCount        *telemetry.Counter
+	waitDuration     *telemetry.Counter
+	queryDuration    *telemetry.Histogram
+
+	mu        sync.Mutex
+	lastStats map[string]sql.DBStats
+}
+
+// NewMetrics initializes a new Metrics that registers its metrics in the given telemetry.Registry.
+func NewMetrics(reg *telemetry.Registry) *Metrics {
+	return &Metrics{
+		openConnections:  reg.Gauge("db_pool_open_connections", "Number of established connections, both in use and idle.", "connection"),
+		inUseConnections: reg.Gauge("db_pool_in_use_connections", "Number of connections currently in use.", "connection"),
+		idleConnections:  reg.Gauge("db_pool_idle_connections", "Number of idle connections.", "connection"),
+		waitCount:        reg.Counter("db_pool_wait_count_total", "Total number of connections waited for.", "connection"),
+		waitDuration:     reg.Counter("db_pool_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", "connection"),
+		queryDuration:    reg.Histogram("db_query_duration_seconds", "Latency of database queries.", nil, "connection", "operation", "table"),
+		lastStats:        make(map[string]sql.DBStats),
+	}
+}
+
+// Instrument registers gorm callbacks in db that measure the latency of every statement, labelled by operation
+// and table. The given name is used to label the connection.
+func (m *Metrics) Instrument(name string, db *gorm.DB) error {
+	cb := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{operation: "create", before: cb.Create().Before("*").Register, after: cb.Create().After("*").Register},
+		{operation: "query", before: cb.Query().Before("*").Register, after: cb.Query().After("*").Register},
+		{operation: "update", before: cb.Update().Before("*").Register, after: cb.Update().After("*").Register},
+		{operation: "delete", before: cb.Delete().Before("*").Register, after: cb.Delete().After("*").Register},
+		{operation: "row", before: cb.Row().Before("*").Register, after: cb.Row().After("*").Register},
+		{operation: "raw", before: cb.Raw().Before("*").Register, after: cb.Raw().After("*").Register},
+	}
+	for _, p := range processors {
+		operation := p.operation
+		err := p.before("jaguar:metrics_before_"+operation, func(tx *gorm.DB) {
+			tx.InstanceSet(metricsStartKey, time.Now())
+		})
+		if err != nil {
+			return err
+		}
+		err = p.after("jaguar:metrics_after_"+operation, func(tx *gorm.DB) {
+			v, ok := tx.InstanceGet(metricsStartKey)
+			if !ok {
+				return
+			}
+			start, ok := v.(time.Time)
+			if !ok {
+				return
+			}
+			m.queryDuration.Observe(time.Since(start).Seconds(), name, operation, tx.Statement.Table)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchStats exports the connection pool statistics of db every interval until the given context is done.
+// The given name is used to label the connection.
+func (m *Metrics) WatchStats(ctx context.Context, name string, db *gorm.DB, interval time.Duration) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.CollectStats(name, sqlDB.Stats())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// CollectStats exports the given connection pool statistics labelled with the given connection name.
+func (m *Metrics) CollectStats(name string, stats sql.DBStats) {
+	m.openConnections.Set(float64(stats.OpenConnections), name)
+	m.inUseConnections.Set(float64(stats.InUse), name)
+	m.idleConnections.Set(float64(stats.Idle), name)
+
+	m.mu.Lock()
+	last := m.lastStats[name]
+	m.lastStats[name] = stats
+	m.mu.Unlock()
+
+	if delta := stats.WaitCount - last.WaitCount; delta >= 0 {
+		m.waitCount.Add(float64(delta), name)
+	}
+	if delta := stats.WaitDuration - last.WaitDuration; delta >= 0 {
+		m.waitDuration.Add(delta.Seconds(), name)
+	}
+}
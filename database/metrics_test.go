@@ -0,0 +1,53 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"github.com/gojaguar/jaguar/config"
+	"github.com/gojaguar/jaguar/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type metricsTest struct {
+	ID   uint
+	Name string
+}
+
+func TestMetrics_Instrument(t *testing.T) {
+	db, err := SetupConnectionSQL(config.Database{Engine: config.EngineSQLite, Name: filepath.Join(t.TempDir(), "metrics")})
+	require.NoError(t, err)
+
+	reg := telemetry.NewRegistry()
+	m := NewMetrics(reg)
+	require.NoError(t, m.Instrument("main", db))
+
+	require.NoError(t, db.AutoMigrate(&metricsTest{}))
+	require.NoError(t, db.Create(&metricsTest{Name: "test"}).Error)
+	var out []metricsTest
+	require.NoError(t, db.Find(&out).Error)
+
+	var buf bytes.Buffer
+	require.NoError(t, reg.WriteText(&buf))
+	assert.Contains(t, buf.String(), `db_query_duration_seconds_count{connection="main",operation="create",table="metrics_tests"} 1`)
+	assert.Contains(t, buf.String(), `db_query_duration_seconds_count{connection="main",operation="query",table="metrics_tests"} 1`)
+}
+
+func TestMetrics_CollectStats(t *testing.T) {
+	reg := telemetry.NewRegistry()
+	m := NewMetrics(reg)
+
+	m.CollectStats("main", sql.DBStats{OpenConnections: 3, InUse: 2, Idle: 1, WaitCount: 4, WaitDuration: time.Second})
+	m.CollectStats("main", sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2, WaitCount: 6, WaitDuration: 3 * time.Second})
+
+	var buf bytes.Buffer
+	require.NoError(t, reg.WriteText(&buf))
+	assert.Contains(t, buf.String(), `db_pool_open_connections{connection="main"} 3`)
+	assert.Contains(t, buf.String(), `db_pool_in_use_connections{connection="main"} 1`)
+	assert.Contains(t, buf.String(), `db_pool_idle_connections{connection="main"} 2`)
+	assert.Contains(t, buf.String(), `db_pool_wait_count_total{connection="main"} 6`)
+	assert.Contains(t, buf.String(), `db_pool_wait_duration_seconds_total{connection="main"} 3`)
+}
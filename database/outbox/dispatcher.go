@@ -0,0 +1,209 @@
+package outbox
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+// Dispatcher polls the outbox table and publishes pending messages using a Publisher.
+//
+// Pending messages are selected with row locks, skipping rows locked by other dispatchers on engines that support
+// it (MySQL and Postgres), which allows running a Dispatcher in every replica of a service.
+type Dispatcher struct {
+	db          *gorm.DB
+	publisher   Publisher
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	backoff     func(attempt int) time.Duration
+	onError     func(err error)
+	now         func() time.Time
+}
+
+// Option configures a Dispatcher.
+type Option func(d *Dispatcher)
+
+// WithInterval sets the time between polls. Defaults to a second, which is kept if interval isn't positive.
+func WithInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) {
+		if interval > 0 {
+			d.interval = interval
+		}
+	}
+}
+
+// WithBatchSize sets the maximum amount of messages published on every poll. Defaults to 100, which is kept if size
+// isn't positive.
+func WithBatchSize(size int) Option {
+	return func(d *Dispatcher) {
+		if size > 0 {
+			d.batchSize = size
+		}
+	}
+}
+
+// WithMaxAttempts sets the amount of failed attempts after which a message is discarded. A value lower than or
+// equal to zero retries messages forever, which is the default.
+func WithMaxAttempts(attempts int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = attempts
+	}
+}
+
+// WithBackoff sets the function that returns how long to wait before retrying a message that failed the given
+// amount of attempts. Defaults to ExponentialBackoff(time.Second, 5*time.Minute). Messages wait at least the poll
+// interval if the function returns a delay that isn't positive, so failing messages aren't retried in a busy loop.
+func WithBackoff(backoff func(attempt int) time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoff = backoff
+	}
+}
+
+// WithErrorHandler sets the function called when a poll fails in Run. Errors are logged by default.
+func WithErrorHandler(handler func(err error)) Option {
+	return func(d *Dispatcher) {
+		d.onError = handler
+	}
+}
+
+// ExponentialBackoff returns a backoff function that doubles the base delay on every attempt, up to max.
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// NewDispatcher initializes a new Dispatcher that publishes the messages stored in db using the given publisher.
+func NewDispatcher(db *gorm.DB, publisher Publisher, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		db:        db,
+		publisher: publisher,
+		interval:  time.Second,
+		batchSize: 100,
+		backoff:   ExponentialBackoff(time.Second, 5*time.Minute),
+		onError: func(err error) {
+			log.Println("Failed to dispatch outbox messages:", err)
+		},
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run polls the outbox table until the given context is canceled. Messages are dispatched as long as the poll
+// finds a full batch, then the Dispatcher waits for the configured interval. A poll that fails also waits for the
+// interval.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := d.Dispatch(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				d.onError(err)
+			}
+			if err != nil || n < d.batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch publishes a single batch of pending messages, it returns the amount of messages processed. Messages
+// that failed to be published are scheduled for a later attempt. Every message is published and marked in its own
+// transaction, so a failure doesn't undo the status of the messages already published.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	var processed int
+	for processed < d.batchSize {
+		found, err := d.dispatchNext(ctx)
+		if err != nil || !found {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// dispatchNext publishes the oldest pending message and stores the result of the attempt in a transaction holding
+// a lock on the message. It returns false if there are no pending messages.
+func (d *Dispatcher) dispatchNext(ctx context.Context) (bool, error) {
+	var found bool
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var msgs []Message
+		query := tx.
+			Where("sent_at IS NULL AND discarded_at IS NULL AND available_at <= ?", d.now()).
+			Order("id").
+			Limit(1)
+		if supportsSkipLocked(tx) {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		if err := query.Find(&msgs).Error; err != nil || len(msgs) == 0 {
+			return err
+		}
+		found = true
+		return d.publish(ctx, tx, msgs[0])
+	})
+	return found && err == nil, err
+}
+
+// publish publishes the given message and stores the result of the attempt.
+func (d *Dispatcher) publish(ctx context.Context, tx *gorm.DB, msg Message) error {
+	now := d.now()
+	values := make(map[string]interface{})
+
+	if err := d.publisher.Publish(ctx, msg); err != nil {
+		attempts := msg.Attempts + 1
+		values["attempts"] = attempts
+		values["last_error"] = err.Error()
+		if d.maxAttempts > 0 && attempts >= d.maxAttempts {
+			values["discarded_at"] = now
+		} else {
+			values["available_at"] = now.Add(d.delay(attempts))
+		}
+	} else {
+		values["sent_at"] = now
+	}
+
+	return tx.Model(&Message{}).Where("id = ?", msg.ID).Updates(values).Error
+}
+
+// delay returns how long to wait before retrying a message that failed the given amount of attempts, which is at
+// least the poll interval.
+func (d *Dispatcher) delay(attempts int) time.Duration {
+	if delay := d.backoff(attempts); delay > 0 {
+		return delay
+	}
+	return d.interval
+}
+
+// supportsSkipLocked returns true if the database behind db supports SELECT ... FOR UPDATE SKIP LOCKED.
+func supportsSkipLocked(db *gorm.DB) bool {
+	switch db.Dialector.Name() {
+	case "mysql", "postgres":
+		return true
+	default:
+		return false
+	}
+}
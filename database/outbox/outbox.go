@@ -0,0 +1,98 @@
+// Package outbox implements the transactional outbox pattern on top of gorm. Events are stored in an outbox table
+// in the same transaction that produced them, and a Dispatcher delivers them to a Publisher once that transaction
+// has been committed.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	// ErrEmptyTopic is returned when enqueuing an event without a topic.
+	ErrEmptyTopic = errors.New("event topic cannot be empty")
+)
+
+// Message is the gorm model used to persist events in the outbox table.
+type Message struct {
+	ID uint `gorm:"primaryKey"`
+	// Topic determines where the message should be published to.
+	Topic string `gorm:"size:255;not null"`
+	// Key is an optional value used by publishers to partition or deduplicate messages.
+	Key string `gorm:"size:255"`
+	// Payload contains the encoded event.
+	Payload []byte
+	// Attempts contains the amount of times the message failed to be published.
+	Attempts int `gorm:"not null;default:0"`
+	// LastError contains the error returned by the last failed attempt.
+	LastError string
+	// AvailableAt determines when the message can be published. It's moved forward after every failed attempt.
+	AvailableAt time.Time `gorm:"index"`
+	// SentAt is set once the message has been published.
+	SentAt *time.Time `gorm:"index"`
+	// DiscardedAt is set when the message exceeded the maximum amount of attempts.
+	DiscardedAt *time.Time
+	CreatedAt   time.Time
+}
+
+// TableName returns the name of the outbox table.
+func (Message) TableName() string {
+	return "outbox_messages"
+}
+
+// Event contains the information of a domain event that should be published.
+type Event struct {
+	Topic   string
+	Key     string
+	Payload []byte
+}
+
+// Publisher delivers outbox messages to a message broker.
+type Publisher interface {
+	// Publish publishes the given message. Returning an error schedules the message to be retried.
+	Publish(ctx context.Context, msg Message) error
+}
+
+// PublisherFunc allows using a function as a Publisher.
+type PublisherFunc func(ctx context.Context, msg Message) error
+
+// Publish calls f(ctx, msg).
+func (f PublisherFunc) Publish(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// Migrate creates or updates the outbox table.
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Message{})
+}
+
+// Enqueue stores the given events in the outbox table. The tx argument should be the transaction that produces
+// the events, so they are only published if that transaction is committed.
+//
+//	err := db.Transaction(func(tx *gorm.DB) error {
+//		if err := tx.Create(&order).Error; err != nil {
+//			return err
+//		}
+//		return outbox.Enqueue(tx, outbox.Event{Topic: "orders.created", Payload: payload})
+//	})
+func Enqueue(tx *gorm.DB, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	msgs := make([]Message, len(events))
+	for i, e := range events {
+		if e.Topic == "" {
+			return ErrEmptyTopic
+		}
+		msgs[i] = Message{
+			Topic:       e.Topic,
+			Key:         e.Key,
+			Payload:     e.Payload,
+			AvailableAt: now,
+		}
+	}
+	return tx.Create(&msgs).Error
+}
@@ -0,0 +1,248 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	suite.Run(t, new(OutboxTestSuite))
+}
+
+type OutboxTestSuite struct {
+	suite.Suite
+
+	db *gorm.DB
+
+	published []Message
+}
+
+func (s *OutboxTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "outbox_test.db")))
+	s.Require().NoError(err)
+	s.Require().NoError(Migrate(db))
+	s.db = db
+	s.published = nil
+}
+
+func (s *OutboxTestSuite) TearDownTest() {
+	sqlDB, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(sqlDB.Close())
+}
+
+func (s *OutboxTestSuite) publisher(fail int) Publisher {
+	return PublisherFunc(func(ctx context.Context, msg Message) error {
+		if fail > 0 {
+			fail--
+			return errors.New("broker unavailable")
+		}
+		s.published = append(s.published, msg)
+		return nil
+	})
+}
+
+func (s *OutboxTestSuite) TestEnqueue_Rollback() {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		s.Require().NoError(Enqueue(tx, Event{Topic: "users.created", Payload: []byte("1")}))
+		return errors.New("rollback")
+	})
+	s.Assert().Error(err)
+
+	var count int64
+	s.Require().NoError(s.db.Model(&Message{}).Count(&count).Error)
+	s.Assert().Zero(count)
+}
+
+func (s *OutboxTestSuite) TestEnqueue_EmptyTopic() {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return Enqueue(tx, Event{Payload: []byte("1")})
+	})
+	s.Assert().ErrorIs(err, ErrEmptyTopic)
+}
+
+func (s *OutboxTestSuite) TestDispatch() {
+	s.Require().NoError(s.db.Transaction(func(tx *gorm.DB) error {
+		return Enqueue(tx,
+			Event{Topic: "users.created", Payload: []byte("1")},
+			Event{Topic: "users.created", Payload: []byte("2")},
+		)
+	}))
+
+	d := NewDispatcher(s.db, s.publisher(0))
+	n, err := d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Equal(2, n)
+	s.Require().Len(s.published, 2)
+	s.Assert().Equal([]byte("1"), s.published[0].Payload)
+	s.Assert().Equal([]byte("2"), s.published[1].Payload)
+
+	var msgs []Message
+	s.Require().NoError(s.db.Find(&msgs).Error)
+	for _, msg := range msgs {
+		s.Assert().NotNil(msg.SentAt)
+	}
+
+	// Sent messages should not be published again.
+	n, err = d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Zero(n)
+}
+
+func (s *OutboxTestSuite) TestDispatch_UpdateFailed() {
+	s.Require().NoError(Enqueue(s.db,
+		Event{Topic: "users.created", Payload: []byte("1")},
+		Event{Topic: "users.created", Payload: []byte("2")},
+	))
+	var updates int
+	errFailed := errors.New("connection reset")
+	s.Require().NoError(s.db.Callback().Update().Before("gorm:update").Register("test:fail", func(db *gorm.DB) {
+		if updates++; updates == 2 {
+			_ = db.AddError(errFailed)
+		}
+	}))
+
+	d := NewDispatcher(s.db, s.publisher(0))
+	n, err := d.Dispatch(context.Background())
+	s.Assert().ErrorIs(err, errFailed)
+	s.Assert().Equal(1, n)
+
+	// Only the message whose status wasn't stored is published again.
+	n, err = d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Equal(1, n)
+	s.Require().Len(s.published, 3)
+	s.Assert().Equal([]byte("2"), s.published[2].Payload)
+}
+
+func (s *OutboxTestSuite) TestRun_DispatchFailed() {
+	s.Require().NoError(Enqueue(s.db, Event{Topic: "users.created"}, Event{Topic: "users.created"}))
+	s.Require().NoError(s.db.Callback().Update().Before("gorm:update").Register("test:fail", func(db *gorm.DB) {
+		_ = db.AddError(errors.New("connection reset"))
+	}))
+
+	var mu sync.Mutex
+	var failures int
+	d := NewDispatcher(s.db, s.publisher(0), WithBatchSize(1), WithInterval(time.Hour), WithErrorHandler(func(err error) {
+		mu.Lock()
+		failures++
+		mu.Unlock()
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Run(ctx)
+	}()
+	s.Require().Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return failures > 0
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	s.Assert().NoError(<-done)
+
+	mu.Lock()
+	defer mu.Unlock()
+	s.Assert().Equal(1, failures)
+	s.Assert().Len(s.published, 1)
+}
+
+func (s *OutboxTestSuite) TestDispatch_Retry() {
+	s.Require().NoError(Enqueue(s.db, Event{Topic: "users.created"}))
+
+	now := time.Now().UTC()
+	d := NewDispatcher(s.db, s.publisher(1), WithBackoff(func(attempt int) time.Duration {
+		return time.Minute
+	}))
+	d.now = func() time.Time { return now }
+
+	_, err := d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Empty(s.published)
+
+	var msg Message
+	s.Require().NoError(s.db.First(&msg).Error)
+	s.Assert().Equal(1, msg.Attempts)
+	s.Assert().Equal("broker unavailable", msg.LastError)
+	s.Assert().Nil(msg.SentAt)
+
+	// The message shouldn't be retried before the backoff expires.
+	n, err := d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Zero(n)
+
+	now = now.Add(time.Minute)
+	n, err = d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Equal(1, n)
+	s.Assert().Len(s.published, 1)
+}
+
+func (s *OutboxTestSuite) TestDispatch_InvalidOptions() {
+	s.Require().NoError(Enqueue(s.db, Event{Topic: "users.created"}))
+
+	now := time.Now().UTC()
+	d := NewDispatcher(s.db, s.publisher(1), WithBatchSize(0), WithInterval(-time.Second), WithBackoff(func(attempt int) time.Duration {
+		return 0
+	}))
+	d.now = func() time.Time { return now }
+	s.Assert().Equal(100, d.batchSize)
+	s.Assert().Equal(time.Second, d.interval)
+
+	n, err := d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Equal(1, n)
+
+	var msg Message
+	s.Require().NoError(s.db.First(&msg).Error)
+	s.Assert().Equal(now.Add(time.Second), msg.AvailableAt.UTC())
+
+	n, err = d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Zero(n)
+}
+
+func (s *OutboxTestSuite) TestDispatch_MaxAttempts() {
+	s.Require().NoError(Enqueue(s.db, Event{Topic: "users.created"}))
+
+	d := NewDispatcher(s.db, s.publisher(1), WithMaxAttempts(1))
+	_, err := d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+
+	var msg Message
+	s.Require().NoError(s.db.First(&msg).Error)
+	s.Assert().NotNil(msg.DiscardedAt)
+
+	n, err := d.Dispatch(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Zero(n)
+}
+
+func (s *OutboxTestSuite) TestRun() {
+	s.Require().NoError(Enqueue(s.db, Event{Topic: "users.created"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := NewDispatcher(s.db, s.publisher(0), WithInterval(10*time.Millisecond))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Run(ctx)
+	}()
+
+	s.Assert().Eventually(func() bool {
+		var count int64
+		err := s.db.Model(&Message{}).Where("sent_at IS NOT NULL").Count(&count).Error
+		return err == nil && count == 1
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	s.Assert().NoError(<-done)
+}
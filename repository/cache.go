@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cache stores values identified by a key. It's used by WithCache to store entities, and it can be implemented
+// using any storage, such as Redis or memcached. Implementations must be safe for concurrent use.
+type Cache[K comparable, V any] interface {
+	// Get returns the value stored with the given key. The boolean is false if the key isn't stored or it expired.
+	Get(ctx context.Context, key K) (V, bool, error)
+	// Set stores value with the given key. The value expires after ttl, or never if ttl is zero.
+	Set(ctx context.Context, key K, value V, ttl time.Duration) error
+	// Delete removes the given keys. Keys that aren't stored are ignored.
+	Delete(ctx context.Context, keys ...K) error
+}
+
+// Cached is the value stored by WithCache for every ID. Found is false if the entity didn't exist when it was
+// loaded, which allows caching not found results.
+type Cached[E any] struct {
+	Value E
+	Found bool
+}
+
+// LRU implements Cache storing up to a fixed amount of values in memory, evicting the least recently used values
+// when it's full. It's safe for concurrent use.
+//
+// Values are deep copied when they are stored and returned, so callers cannot mutate the cached values.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[K]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+// lruEntry is a value stored in an LRU.
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// Get returns the value stored with the given key. The boolean is false if the key isn't stored or it expired.
+func (c *LRU[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zero V
+	element, ok := c.items[key]
+	if !ok {
+		return zero, false, nil
+	}
+	entry := element.Value.(*lruEntry[K, V])
+	if !entry.expires.IsZero() && !c.now().Before(entry.expires) {
+		c.remove(element)
+		return zero, false, nil
+	}
+	c.order.MoveToFront(element)
+	return deepCopy(entry.value), true, nil
+}
+
+// Set stores value with the given key, evicting the least recently used value if the cache is full. The value
+// expires after ttl, or never if ttl is zero.
+func (c *LRU[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &lruEntry[K, V]{key: key, value: deepCopy(value)}
+	if ttl > 0 {
+		entry.expires = c.now().Add(ttl)
+	}
+	if element, ok := c.items[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return nil
+	}
+	c.items[key] = c.order.PushFront(entry)
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Delete removes the given keys. Keys that aren't stored are ignored.
+func (c *LRU[K, V]) Delete(ctx context.Context, keys ...K) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if element, ok := c.items[key]; ok {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+// Len returns the amount of values stored, including the expired values that weren't evicted yet.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove removes the given element from the cache.
+func (c *LRU[K, V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*lruEntry[K, V]).key)
+}
+
+// NewLRU initializes a new LRU storing up to capacity values. If capacity is zero or negative, values are only
+// removed when they expire or are deleted.
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	return &LRU[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// detachedContext is a context holding the values of its parent, but not its deadline nor its cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+// Deadline returns no deadline.
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil, since the context is never canceled.
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil, since the context is never canceled.
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Value returns the value of the parent context associated with key.
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// flight collapses concurrent calls loading the same key into a single call.
+type flight[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+// flightCall is a call in progress, or completed, whose result is shared by every caller.
+type flightCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// do calls fn and returns its result. If a call with the same key is in progress, do waits for it and returns its
+// result instead. Calls run in the background with a context holding the values of ctx but not its cancellation, so
+// a caller whose ctx is done returns early with the context error without affecting the other callers. A panic in fn
+// is returned to every caller as an error.
+func (f *flight[K, V]) do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[K]*flightCall[V])
+	}
+	c, ok := f.calls[key]
+	if !ok {
+		c = &flightCall[V]{done: make(chan struct{})}
+		f.calls[key] = c
+		go f.run(detachedContext{parent: ctx}, key, c, fn)
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return deepCopy(c.value), c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// run calls fn, storing its result in c.
+func (f *flight[K, V]) run(ctx context.Context, key K, c *flightCall[V], fn func(ctx context.Context) (V, error)) {
+	defer func() {
+		if p := recover(); p != nil {
+			var zero V
+			c.value, c.err = zero, fmt.Errorf("load panicked: %v", p)
+		}
+		f.mu.Lock()
+		if f.calls[key] == c {
+			delete(f.calls, key)
+		}
+		f.mu.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = fn(ctx)
+}
+
+// forget makes the calls in progress for the given keys unavailable to new callers, which start a new call.
+func (f *flight[K, V]) forget(keys ...K) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		delete(f.calls, key)
+	}
+}
@@ -0,0 +1,395 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingRepository counts the calls to Get, blocking them until release is closed if it's not nil.
+type countingRepository struct {
+	Repository[Test, uint]
+	gets    int64
+	release chan struct{}
+}
+
+func (r *countingRepository) Get(ctx context.Context, id uint, opts ...ReadOption) (Test, error) {
+	atomic.AddInt64(&r.gets, 1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.Repository.Get(ctx, id, opts...)
+}
+
+func TestCache(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
+
+type CacheTestSuite struct {
+	suite.Suite
+
+	backend    *countingRepository
+	cache      *LRU[uint, Cached[Test]]
+	repository Repository[Test, uint]
+}
+
+func (s *CacheTestSuite) SetupTest() {
+	s.backend = &countingRepository{Repository: NewMemory[Test, uint]()}
+	s.cache = NewLRU[uint, Cached[Test]](10)
+	s.repository = WithCache[Test, uint](s.backend, s.cache, CacheOptions{
+		TTL:         time.Minute,
+		NegativeTTL: time.Minute,
+	})
+}
+
+func (s *CacheTestSuite) TestGet() {
+	ctx := context.Background()
+	created, err := s.repository.Create(ctx, Test{FirstName: "Marcos"})
+	s.Require().NoError(err)
+
+	for i := 0; i < 3; i++ {
+		result, err := s.repository.Get(ctx, created.ID)
+		s.Require().NoError(err)
+		s.Assert().Equal("Marcos", result.FirstName)
+	}
+	s.Assert().Equal(int64(1), s.backend.gets)
+
+	_, err = s.repository.Get(ctx, created.ID, WithPreload("Unknown"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+	s.Assert().Equal(int64(2), s.backend.gets)
+}
+
+func (s *CacheTestSuite) TestGet_NotFound() {
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := s.repository.Get(ctx, 1)
+		s.Assert().ErrorIs(err, ErrNotFound)
+	}
+	s.Assert().Equal(int64(1), s.backend.gets)
+
+	created, err := s.repository.Create(ctx, Test{FirstName: "Marcos"})
+	s.Require().NoError(err)
+	s.Require().Equal(uint(1), created.ID)
+
+	result, err := s.repository.Get(ctx, 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("Marcos", result.FirstName)
+	s.Assert().Equal(int64(2), s.backend.gets)
+}
+
+func (s *CacheTestSuite) TestGet_NegativeTTLDisabled() {
+	s.repository = WithCache[Test, uint](s.backend, s.cache, CacheOptions{})
+	for i := 0; i < 2; i++ {
+		_, err := s.repository.Get(context.Background(), 1)
+		s.Assert().ErrorIs(err, ErrNotFound)
+	}
+	s.Assert().Equal(int64(2), s.backend.gets)
+}
+
+func (s *CacheTestSuite) TestGet_Copy() {
+	ctx := context.Background()
+	created, err := s.repository.Create(ctx, Test{FirstName: "Marcos"})
+	s.Require().NoError(err)
+
+	result, err := s.repository.Get(ctx, created.ID)
+	s.Require().NoError(err)
+	result.FirstName = "Mutated"
+
+	result, err = s.repository.Get(ctx, created.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal("Marcos", result.FirstName)
+}
+
+func (s *CacheTestSuite) TestGet_Singleflight() {
+	ctx := context.Background()
+	created, err := s.repository.Create(ctx, Test{FirstName: "Marcos"})
+	s.Require().NoError(err)
+	s.backend.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make([]Test, 10)
+	errs := make([]error, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.repository.Get(ctx, created.ID)
+		}(i)
+	}
+	s.Eventually(func() bool {
+		return atomic.LoadInt64(&s.backend.gets) == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(s.backend.release)
+	wg.Wait()
+
+	for i := range results {
+		s.Require().NoError(errs[i])
+		s.Assert().Equal("Marcos", results[i].FirstName)
+	}
+	s.Assert().Equal(int64(1), s.backend.gets)
+}
+
+func (s *CacheTestSuite) TestGet_SingleflightCanceled() {
+	created, err := s.repository.Create(context.Background(), Test{FirstName: "Marcos"})
+	s.Require().NoError(err)
+	s.backend.release = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := s.repository.Get(ctx, created.ID)
+		first <- err
+	}()
+	s.Eventually(func() bool {
+		return atomic.LoadInt64(&s.backend.gets) == 1
+	}, time.Second, time.Millisecond)
+
+	second := make(chan error, 1)
+	var result Test
+	go func() {
+		var err error
+		result, err = s.repository.Get(context.Background(), created.ID)
+		second <- err
+	}()
+	cancel()
+	s.Assert().ErrorIs(<-first, context.Canceled)
+	close(s.backend.release)
+	s.Require().NoError(<-second)
+	s.Assert().Equal("Marcos", result.FirstName)
+	s.Assert().Equal(int64(1), s.backend.gets)
+}
+
+// panickingRepository panics when calling Get, after blocking until release is closed.
+type panickingRepository struct {
+	Repository[Test, uint]
+	release chan struct{}
+}
+
+func (r *panickingRepository) Get(ctx context.Context, id uint, opts ...ReadOption) (Test, error) {
+	<-r.release
+	panic("failed")
+}
+
+func (s *CacheTestSuite) TestGet_Panic() {
+	backend := &panickingRepository{Repository: NewMemory[Test, uint](), release: make(chan struct{})}
+	repository := WithCache[Test, uint](backend, s.cache, CacheOptions{})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = repository.Get(context.Background(), 1)
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+	for _, err := range errs {
+		s.Assert().ErrorContains(err, "panicked")
+	}
+}
+
+func (s *CacheTestSuite) TestInvalidation() {
+	ctx := context.Background()
+	entities, err := s.repository.CreateBulk(ctx, []Test{{FirstName: "Marcos"}, {FirstName: "Andres"}, {FirstName: "Andrew"}})
+	s.Require().NoError(err)
+	load := func() {
+		for _, e := range entities {
+			_, _ = s.repository.Get(ctx, e.ID)
+		}
+		s.Require().Equal(3, s.cache.Len())
+	}
+
+	load()
+	_, err = s.repository.Update(ctx, entities[0].ID, Test{FirstName: "Mark"})
+	s.Require().NoError(err)
+	result, err := s.repository.Get(ctx, entities[0].ID)
+	s.Require().NoError(err)
+	s.Assert().Equal("Mark", result.FirstName)
+
+	load()
+	_, err = s.repository.Patch(ctx, entities[0].ID, Mask(Test{}, "FirstName"))
+	s.Require().NoError(err)
+	s.Assert().Equal(2, s.cache.Len())
+
+	load()
+	_, err = s.repository.UpdateBulk(ctx, []uint{entities[0].ID, entities[1].ID}, Test{LastName: "Huck"})
+	s.Require().NoError(err)
+	s.Assert().Equal(1, s.cache.Len())
+
+	load()
+	_, err = s.repository.Upsert(ctx, Test{Model: entities[2].Model, FirstName: "Andy"})
+	s.Require().NoError(err)
+	s.Assert().Equal(2, s.cache.Len())
+
+	load()
+	_, err = s.repository.Remove(ctx, entities[0].ID)
+	s.Require().NoError(err)
+	_, err = s.repository.Get(ctx, entities[0].ID)
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	_, err = s.repository.RemoveBulk(ctx, []uint{entities[1].ID, entities[2].ID})
+	s.Require().NoError(err)
+	for _, e := range entities {
+		_, err = s.repository.Get(ctx, e.ID)
+		s.Assert().ErrorIs(err, ErrNotFound)
+	}
+}
+
+func (s *CacheTestSuite) TestInvalidation_DuringLoad() {
+	ctx := context.Background()
+	created, err := s.repository.Create(ctx, Test{FirstName: "Marcos"})
+	s.Require().NoError(err)
+	s.backend.release = make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = s.repository.Get(ctx, created.ID)
+	}()
+	s.Eventually(func() bool {
+		return atomic.LoadInt64(&s.backend.gets) == 1
+	}, time.Second, time.Millisecond)
+	_, err = s.repository.Update(ctx, created.ID, Test{FirstName: "Mark"})
+	s.Require().NoError(err)
+	close(s.backend.release)
+	<-done
+
+	s.Assert().Zero(s.cache.Len())
+}
+
+func (s *CacheTestSuite) TestTenant() {
+	backend := NewMemory[Document, uint]()
+	_, err := backend.CreateBulk(context.Background(), []Document{{TenantID: "acme", Title: "Plans"}, {TenantID: "globex"}})
+	s.Require().NoError(err)
+	acme := ContextWithTenant(context.Background(), "acme")
+	globex := ContextWithTenant(context.Background(), "globex")
+
+	cache := NewLRU[uint, Cached[Document]](10)
+	inner := WithTenant[Document, uint, string](WithCache[Document, uint](backend, cache, CacheOptions{}), "tenant_id")
+	result, err := inner.Get(acme, 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("Plans", result.Title)
+	s.Assert().Equal(1, cache.Len())
+	_, err = inner.Get(globex, 1)
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	cache = NewLRU[uint, Cached[Document]](10)
+	outer := WithCache[Document, uint](WithTenant[Document, uint, string](backend, "tenant_id"), cache, CacheOptions{})
+	_, err = outer.Get(acme, 1)
+	s.Require().NoError(err)
+	_, err = outer.Get(globex, 1)
+	s.Assert().ErrorIs(err, ErrNotFound)
+	s.Assert().Zero(cache.Len())
+}
+
+func (s *CacheTestSuite) TestMissingPrimaryKey() {
+	ctx := context.Background()
+	backend := NewMemory[Profile, uint](WithPrimaryKey("UserID"))
+	repository := WithCache[Profile, uint](backend, NewLRU[uint, Cached[Profile]](10), CacheOptions{})
+	_, err := repository.Create(ctx, Profile{UserID: 1})
+	s.Assert().ErrorIs(err, ErrMissingPrimaryKey)
+	_, err = repository.Upsert(ctx, Profile{UserID: 1})
+	s.Assert().ErrorIs(err, ErrMissingPrimaryKey)
+	list, err := backend.List(ctx, Query{})
+	s.Require().NoError(err)
+	s.Assert().Empty(list)
+
+	repository = WithCache[Profile, uint](backend, NewLRU[uint, Cached[Profile]](10), CacheOptions{PrimaryKey: "UserID"})
+	_, err = repository.Create(ctx, Profile{UserID: 1})
+	s.Require().NoError(err)
+}
+
+type failingCache struct {
+	Cache[uint, Cached[Test]]
+}
+
+func (failingCache) Get(ctx context.Context, key uint) (Cached[Test], bool, error) {
+	return Cached[Test]{}, false, errors.New("unavailable")
+}
+
+func (failingCache) Set(ctx context.Context, key uint, value Cached[Test], ttl time.Duration) error {
+	return errors.New("unavailable")
+}
+
+func (failingCache) Delete(ctx context.Context, keys ...uint) error {
+	return errors.New("unavailable")
+}
+
+func (s *CacheTestSuite) TestCacheErrors() {
+	ctx := context.Background()
+	s.repository = WithCache[Test, uint](s.backend, failingCache{}, CacheOptions{})
+	created, err := s.repository.Create(ctx, Test{FirstName: "Marcos"})
+	s.Assert().ErrorContains(err, "failed to invalidate cache")
+	s.Assert().Equal("Marcos", created.FirstName)
+
+	result, err := s.repository.Get(ctx, created.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal("Marcos", result.FirstName)
+}
+
+func TestLRU(t *testing.T) {
+	suite.Run(t, new(LRUTestSuite))
+}
+
+type LRUTestSuite struct {
+	suite.Suite
+
+	now   time.Time
+	cache *LRU[string, int]
+}
+
+func (s *LRUTestSuite) SetupTest() {
+	s.now = time.Now()
+	s.cache = NewLRU[string, int](2)
+	s.cache.now = func() time.Time {
+		return s.now
+	}
+}
+
+func (s *LRUTestSuite) TestEviction() {
+	ctx := context.Background()
+	s.Require().NoError(s.cache.Set(ctx, "a", 1, 0))
+	s.Require().NoError(s.cache.Set(ctx, "b", 2, 0))
+	_, ok, err := s.cache.Get(ctx, "a")
+	s.Require().NoError(err)
+	s.Require().True(ok)
+
+	s.Require().NoError(s.cache.Set(ctx, "c", 3, 0))
+	_, ok, _ = s.cache.Get(ctx, "b")
+	s.Assert().False(ok)
+	value, ok, _ := s.cache.Get(ctx, "a")
+	s.Assert().True(ok)
+	s.Assert().Equal(1, value)
+	s.Assert().Equal(2, s.cache.Len())
+}
+
+func (s *LRUTestSuite) TestExpiration() {
+	ctx := context.Background()
+	s.Require().NoError(s.cache.Set(ctx, "a", 1, time.Second))
+	s.Require().NoError(s.cache.Set(ctx, "b", 2, 0))
+
+	s.now = s.now.Add(time.Second)
+	_, ok, _ := s.cache.Get(ctx, "a")
+	s.Assert().False(ok)
+	_, ok, _ = s.cache.Get(ctx, "b")
+	s.Assert().True(ok)
+	s.Assert().Equal(1, s.cache.Len())
+}
+
+func (s *LRUTestSuite) TestDelete() {
+	ctx := context.Background()
+	s.Require().NoError(s.cache.Set(ctx, "a", 1, 0))
+	s.Require().NoError(s.cache.Set(ctx, "a", 2, 0))
+	s.Require().NoError(s.cache.Delete(ctx, "a", "unknown"))
+	_, ok, _ := s.cache.Get(ctx, "a")
+	s.Assert().False(ok)
+	s.Assert().Zero(s.cache.Len())
+}
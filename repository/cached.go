@@ -0,0 +1,326 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// CacheOptions configures the cache used by WithCache.
+type CacheOptions struct {
+	// TTL is the duration entities are cached for. If zero, entities are cached until they are evicted or
+	// invalidated.
+	TTL time.Duration
+	// NegativeTTL is the duration not found results are cached for. If zero, not found results aren't cached.
+	NegativeTTL time.Duration
+	// PrimaryKey sets the primary key field, the same way WithPrimaryKey does. If empty, the primary key is read
+	// from the entity schema.
+	PrimaryKey string
+}
+
+// cachedRepository is a Repository caching the entities returned by Get.
+type cachedRepository[E any, K comparable] struct {
+	Repository[E, K]
+	cache   Cache[K, Cached[E]]
+	options CacheOptions
+
+	schema *schema.Schema
+	pk     *schema.Field
+	err    error
+
+	flight flight[K, E]
+	// mu serializes cache writes with invalidations, and epoch counts the invalidations, so entities loaded
+	// before an invalidation aren't written to the cache after it.
+	mu    sync.Mutex
+	epoch uint64
+}
+
+// parse resolves the primary key of the entity, used to invalidate the entities written to the repository.
+func (r *cachedRepository[E, K]) parse() error {
+	s, err := schema.Parse(new(E), &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	r.schema = s
+	r.pk, err = primaryKey(s, r.options.PrimaryKey)
+	return err
+}
+
+// cacheable reports whether a call to Get with ctx can use the cache. Entities are cached by ID regardless of their
+// tenant, so calls whose context holds a tenant are only cached if they are scoped, which happens when the cache is
+// wrapped by WithTenant.
+func (r *cachedRepository[E, K]) cacheable(ctx context.Context) bool {
+	return ctx.Value(tenantKey{}) == nil || scopeFromContext(ctx, r.schema) != nil
+}
+
+// Get returns an entity identified by its ID, reading it from the cache if possible. Calls with read options
+// aren't cached.
+func (r *cachedRepository[E, K]) Get(ctx context.Context, id K, opts ...ReadOption) (E, error) {
+	var zero E
+	if r.err != nil {
+		return zero, r.err
+	}
+	if len(opts) > 0 || !r.cacheable(ctx) {
+		return r.Repository.Get(ctx, id, opts...)
+	}
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	// The cache is an optimization, so errors reading from it fall back to the repository.
+	if cached, ok, err := r.cache.Get(ctx, id); err == nil && ok {
+		if !cached.Found {
+			return zero, r.notFound(id)
+		}
+		return r.scoped(ctx, id, cached.Value)
+	}
+	// Entities are loaded and cached regardless of the scope of ctx, which is checked once they are loaded.
+	entity, err := r.flight.do(ctx, id, func(ctx context.Context) (E, error) {
+		return r.load(contextWithoutScope(ctx, r.schema), id)
+	})
+	if err != nil {
+		return zero, err
+	}
+	return r.scoped(ctx, id, entity)
+}
+
+// scoped returns entity if it matches the scope held by ctx, and ErrNotFound otherwise.
+func (r *cachedRepository[E, K]) scoped(ctx context.Context, id K, entity E) (E, error) {
+	pred, err := compileFilter(r.schema, scopeFromContext(ctx, r.schema))
+	if err != nil {
+		var zero E
+		return zero, err
+	}
+	if pred != nil && !pred(ctx, reflect.ValueOf(&entity).Elem()) {
+		var zero E
+		return zero, r.notFound(id)
+	}
+	return entity, nil
+}
+
+// notFound returns the error returned for the ID of an entity that doesn't exist.
+func (r *cachedRepository[E, K]) notFound(id K) error {
+	return fmt.Errorf("%w: %s %v", ErrNotFound, r.schema.Name, id)
+}
+
+// load reads the entity identified by id from the repository and caches the result.
+func (r *cachedRepository[E, K]) load(ctx context.Context, id K) (E, error) {
+	r.mu.Lock()
+	epoch := r.epoch
+	r.mu.Unlock()
+
+	entity, err := r.Repository.Get(ctx, id)
+	switch {
+	case err == nil:
+		r.store(ctx, epoch, id, Cached[E]{Value: entity, Found: true}, r.options.TTL)
+	case errors.Is(err, ErrNotFound) && r.options.NegativeTTL > 0:
+		r.store(ctx, epoch, id, Cached[E]{}, r.options.NegativeTTL)
+	}
+	return entity, err
+}
+
+// store writes value to the cache, unless an invalidation happened since epoch.
+func (r *cachedRepository[E, K]) store(ctx context.Context, epoch uint64, id K, value Cached[E], ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.epoch != epoch {
+		return
+	}
+	// Failing to cache an entity doesn't affect the result.
+	_ = r.cache.Set(ctx, id, value, ttl)
+}
+
+// invalidate removes the given IDs from the cache.
+func (r *cachedRepository[E, K]) invalidate(ctx context.Context, ids ...K) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.epoch++
+	r.flight.forget(ids...)
+	if err := r.cache.Delete(ctx, ids...); err != nil {
+		return fmt.Errorf("failed to invalidate cache: %w", err)
+	}
+	return nil
+}
+
+// invalidateEntities removes the IDs of the given entities from the cache. Entities without ID are ignored.
+func (r *cachedRepository[E, K]) invalidateEntities(ctx context.Context, entities ...E) error {
+	var zero K
+	ids := make([]K, 0, len(entities))
+	for i := range entities {
+		if id := primaryKeyValue[K](ctx, r.pk, reflect.ValueOf(&entities[i]).Elem()); id != zero {
+			ids = append(ids, id)
+		}
+	}
+	return r.invalidate(ctx, ids...)
+}
+
+// Create creates an entity, invalidating its ID in case a not found result was cached.
+func (r *cachedRepository[E, K]) Create(ctx context.Context, entity E) (E, error) {
+	if r.err != nil {
+		var zero E
+		return zero, r.err
+	}
+	created, err := r.Repository.Create(ctx, entity)
+	if err != nil {
+		return created, err
+	}
+	return created, r.invalidateEntities(ctx, created)
+}
+
+// CreateBulk creates a set of entities, invalidating their IDs in case not found results were cached.
+func (r *cachedRepository[E, K]) CreateBulk(ctx context.Context, entities []E) ([]E, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	created, err := r.Repository.CreateBulk(ctx, entities)
+	return created, firstError(err, r.invalidateEntities(ctx, created...))
+}
+
+// Upsert creates or updates an entity, invalidating its ID.
+func (r *cachedRepository[E, K]) Upsert(ctx context.Context, entity E, opts ...UpsertOption) (E, error) {
+	if r.err != nil {
+		var zero E
+		return zero, r.err
+	}
+	upserted, err := r.Repository.Upsert(ctx, entity, opts...)
+	return upserted, firstError(err, r.invalidateEntities(ctx, entity, upserted))
+}
+
+// UpsertBulk creates or updates a set of entities, invalidating their IDs.
+func (r *cachedRepository[E, K]) UpsertBulk(ctx context.Context, entities []E, opts ...UpsertOption) ([]E, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	upserted, err := r.Repository.UpsertBulk(ctx, entities, opts...)
+	return upserted, firstError(err, r.invalidateEntities(ctx, entities...), r.invalidateEntities(ctx, upserted...))
+}
+
+// Update updates an entity, invalidating its ID.
+func (r *cachedRepository[E, K]) Update(ctx context.Context, id K, entity E) (E, error) {
+	if r.err != nil {
+		var zero E
+		return zero, r.err
+	}
+	updated, err := r.Repository.Update(ctx, id, entity)
+	return updated, firstError(err, r.invalidate(ctx, id))
+}
+
+// Patch updates the fields of an entity listed in patch, invalidating its ID.
+func (r *cachedRepository[E, K]) Patch(ctx context.Context, id K, patch Patch[E]) (E, error) {
+	if r.err != nil {
+		var zero E
+		return zero, r.err
+	}
+	patched, err := r.Repository.Patch(ctx, id, patch)
+	return patched, firstError(err, r.invalidate(ctx, id))
+}
+
+// UpdateBulk updates multiple entities, invalidating their IDs.
+func (r *cachedRepository[E, K]) UpdateBulk(ctx context.Context, ids []K, entity E) (Result[E, K], error) {
+	if r.err != nil {
+		return Result[E, K]{}, r.err
+	}
+	result, err := r.Repository.UpdateBulk(ctx, ids, entity)
+	return result, firstError(err, r.invalidate(ctx, ids...))
+}
+
+// Remove removes an entity, invalidating its ID.
+func (r *cachedRepository[E, K]) Remove(ctx context.Context, id K) (E, error) {
+	if r.err != nil {
+		var zero E
+		return zero, r.err
+	}
+	removed, err := r.Repository.Remove(ctx, id)
+	return removed, firstError(err, r.invalidate(ctx, id))
+}
+
+// RemoveBulk removes a set of entities, invalidating their IDs.
+func (r *cachedRepository[E, K]) RemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	if r.err != nil {
+		return Result[E, K]{}, r.err
+	}
+	result, err := r.Repository.RemoveBulk(ctx, ids)
+	return result, firstError(err, r.invalidate(ctx, ids...))
+}
+
+// Restore restores a soft deleted entity, invalidating its ID.
+func (r *cachedRepository[E, K]) Restore(ctx context.Context, id K) (E, error) {
+	if r.err != nil {
+		var zero E
+		return zero, r.err
+	}
+	restored, err := r.Repository.Restore(ctx, id)
+	return restored, firstError(err, r.invalidate(ctx, id))
+}
+
+// RestoreBulk restores a set of soft deleted entities, invalidating their IDs.
+func (r *cachedRepository[E, K]) RestoreBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	if r.err != nil {
+		return Result[E, K]{}, r.err
+	}
+	result, err := r.Repository.RestoreBulk(ctx, ids)
+	return result, firstError(err, r.invalidate(ctx, ids...))
+}
+
+// ForceRemove permanently removes an entity, invalidating its ID.
+func (r *cachedRepository[E, K]) ForceRemove(ctx context.Context, id K) (E, error) {
+	if r.err != nil {
+		var zero E
+		return zero, r.err
+	}
+	removed, err := r.Repository.ForceRemove(ctx, id)
+	return removed, firstError(err, r.invalidate(ctx, id))
+}
+
+// ForceRemoveBulk permanently removes a set of entities, invalidating their IDs.
+func (r *cachedRepository[E, K]) ForceRemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	if r.err != nil {
+		return Result[E, K]{}, r.err
+	}
+	result, err := r.Repository.ForceRemoveBulk(ctx, ids)
+	return result, firstError(err, r.invalidate(ctx, ids...))
+}
+
+// firstError returns the first non-nil error of errs.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithCache wraps repository, caching the entities returned by Get in cache. Not found results are cached too if
+// opts.NegativeTTL is set, and concurrent calls loading the same ID are collapsed into a single call to repository.
+//
+// Entities are invalidated when they are written through the returned Repository, but changes made by other
+// processes or repositories are only visible once the cached entities expire. Calls with read options, and the
+// methods reading multiple entities, aren't cached.
+//
+// The primary key of the entity is resolved when wrapping the repository. If it cannot be resolved, Get and the
+// write methods return ErrMissingPrimaryKey without calling repository, and it must be set using opts.PrimaryKey.
+//
+// Entities are cached by ID, so a repository wrapped by WithTenant must wrap the cache, not the other way around:
+// WithTenant(WithCache(repository)). The cache then checks the tenant of cached entities. Calls whose context holds
+// a tenant aren't cached otherwise.
+//
+//	users := repository.WithCache[User, uint](repository.NewRepositorySQL[User, uint](db), repository.NewLRU[uint, repository.Cached[User]](1000), repository.CacheOptions{
+//		TTL:         time.Minute,
+//		NegativeTTL: 10 * time.Second,
+//	})
+func WithCache[E any, K comparable](repository Repository[E, K], cache Cache[K, Cached[E]], opts CacheOptions) Repository[E, K] {
+	r := &cachedRepository[E, K]{
+		Repository: repository,
+		cache:      cache,
+		options:    opts,
+	}
+	r.err = r.parse()
+	return r
+}
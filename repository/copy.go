@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"reflect"
+)
+
+// deepCopy returns a copy of value that doesn't share memory with it. Unexported struct fields are copied
+// shallowly, and pointer cycles are preserved.
+func deepCopy[T any](value T) T {
+	src := reflect.ValueOf(&value).Elem()
+	dst := reflect.New(src.Type()).Elem()
+	c := copier{seen: make(map[copied]reflect.Value)}
+	c.copy(dst, src)
+	return dst.Interface().(T)
+}
+
+// copied identifies a pointer that was already copied.
+type copied struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// copier deep copies values, keeping track of the copied pointers.
+type copier struct {
+	seen map[copied]reflect.Value
+}
+
+// copy deep copies src into dst, which must be settable.
+func (c copier) copy(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return
+		}
+		key := copied{ptr: src.Pointer(), typ: src.Type()}
+		if p, ok := c.seen[key]; ok {
+			dst.Set(p)
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		c.seen[key] = p
+		c.copy(p.Elem(), src.Elem())
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		v := reflect.New(src.Elem().Type()).Elem()
+		c.copy(v, src.Elem())
+		dst.Set(v)
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				c.copy(dst.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			c.copy(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			c.copy(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			v := reflect.New(src.Type().Elem()).Elem()
+			c.copy(v, iter.Value())
+			m.SetMapIndex(iter.Key(), v)
+		}
+		dst.Set(m)
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when an entity doesn't exist in the persistence layer.
+	ErrNotFound = errors.New("entity not found")
+
+	// ErrConflict is returned when an entity conflicts with an existing one, such as when violating a primary key
+	// or a unique constraint.
+	ErrConflict = errors.New("entity conflicts with an existing entity")
+
+	// ErrDuplicate is an alias of ErrConflict.
+	ErrDuplicate = ErrConflict
+
+	// ErrConstraint is returned when an entity violates a constraint other than a unique constraint, such as
+	// foreign key, not null or check constraints.
+	ErrConstraint = errors.New("entity violates a constraint")
+
+	// ErrStale is returned when an operation conflicts with a concurrent modification of the same entities. It's
+	// usually safe to retry the operation.
+	ErrStale = errors.New("entity was modified concurrently")
+
+	// ErrDeadlock is returned when the database aborted a transaction to resolve a deadlock. The transaction was
+	// rolled back, so it can be retried as a whole.
+	ErrDeadlock = errors.New("transaction aborted by a deadlock")
+
+	// ErrInvalidField is returned when referencing a field that doesn't exist in an entity.
+	ErrInvalidField = errors.New("invalid field")
+
+	// ErrMissingPrimaryKey is returned when the primary key of an entity cannot be determined.
+	ErrMissingPrimaryKey = errors.New("entity has no primary key")
+
+	// ErrStop is returned by the function passed to Repository.Each to stop the iteration early. It's not returned
+	// by Each.
+	ErrStop = errors.New("stop iteration")
+
+	// ErrInvalidCursor is returned when a pagination cursor is malformed or was produced by a different query.
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrNotSoftDeletable is returned when restoring entities, or reading soft deleted entities, whose model has no
+	// gorm.DeletedAt field.
+	ErrNotSoftDeletable = errors.New("entity cannot be soft deleted")
+
+	// ErrMissingTenant is returned by repositories wrapped using WithTenant when the context has no tenant.
+	ErrMissingTenant = errors.New("missing tenant")
+
+	// ErrTenantMismatch is returned by repositories wrapped using WithTenant when writing an entity that belongs to
+	// a different tenant than the one in the context.
+	ErrTenantMismatch = errors.New("entity belongs to a different tenant")
+)
+
+// ItemError contains the error of a single item of a bulk operation.
+type ItemError struct {
+	// Index contains the position of the item in the input of the bulk operation.
+	Index int
+	// Err contains the error returned when processing the item.
+	Err error
+}
+
+// Error returns the error message.
+func (e ItemError) Error() string {
+	return fmt.Sprintf("item %d: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the error returned when processing the item.
+func (e ItemError) Unwrap() error {
+	return e.Err
+}
+
+// BulkError is returned by bulk operations in best-effort mode when some of the items failed. The items that
+// succeeded are returned along with it.
+type BulkError struct {
+	// Errors contains the error of every item that failed, in input order.
+	Errors []ItemError
+}
+
+// Error returns the error message.
+func (e *BulkError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d items failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Is returns true if the error of any of the failed items matches target.
+func (e *BulkError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// translatedError is a gorm or database driver error translated into an error defined by this package. It matches
+// both errors, so callers can still inspect the driver error using errors.As.
+type translatedError struct {
+	target error
+	err    error
+}
+
+// Error returns the error message.
+func (e *translatedError) Error() string {
+	return fmt.Sprintf("%s: %s", e.target, e.err)
+}
+
+// Is returns true if target is the error defined by this package.
+func (e *translatedError) Is(target error) bool {
+	return target == e.target
+}
+
+// Unwrap returns the gorm or database driver error.
+func (e *translatedError) Unwrap() error {
+	return e.err
+}
+
+// translateError converts gorm and database driver errors into the errors defined by this package. Errors that
+// cannot be translated are returned as they are.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if target := translate(err); target != nil {
+		return &translatedError{target: target, err: err}
+	}
+	return err
+}
+
+// translate returns the error defined by this package that corresponds to err, or nil if there's none.
+func translate(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrConflict
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return translateMySQL(mysqlErr.Number)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return translatePostgres(pgErr.Code)
+	}
+
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if code, extended, ok := sqliteCodes(e); ok {
+			return translateSQLite(code, extended)
+		}
+	}
+
+	return nil
+}
+
+// sqlitePackage is the import path of the SQLite driver used by gorm.
+const sqlitePackage = "github.com/mattn/go-sqlite3"
+
+// sqliteCodes returns the result codes of a SQLite driver error. The driver isn't imported because it requires
+// cgo, which would make every user of this package depend on cgo. Instead, the codes are read from the int
+// fields Code and ExtendedCode of the Error struct declared in sqlitePackage, which is what the driver returns
+// either as a value or as a pointer. TestSQLiteCodes fails if the driver changes that contract.
+func sqliteCodes(err error) (code, extended int64, ok bool) {
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, 0, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.Type().PkgPath() != sqlitePackage || v.Type().Name() != "Error" {
+		return 0, 0, false
+	}
+	c, e := v.FieldByName("Code"), v.FieldByName("ExtendedCode")
+	if !c.IsValid() || !e.IsValid() || c.Kind() != reflect.Int || e.Kind() != reflect.Int {
+		return 0, 0, false
+	}
+	return c.Int(), e.Int(), true
+}
+
+// translateMySQL translates MySQL error numbers.
+// Reference: https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+func translateMySQL(number uint16) error {
+	switch number {
+	case 1062, 1586: // ER_DUP_ENTRY, ER_DUP_ENTRY_WITH_KEY_NAME
+		return ErrConflict
+	case 1048, 1216, 1217, 1451, 1452, 3819: // ER_BAD_NULL_ERROR, ER_NO_REFERENCED_ROW, ER_ROW_IS_REFERENCED, ER_ROW_IS_REFERENCED_2, ER_NO_REFERENCED_ROW_2, ER_CHECK_CONSTRAINT_VIOLATED
+		return ErrConstraint
+	case 1213: // ER_LOCK_DEADLOCK
+		return ErrDeadlock
+	default:
+		return nil
+	}
+}
+
+// translatePostgres translates Postgres SQLSTATE codes.
+// Reference: https://www.postgresql.org/docs/current/errcodes-appendix.html
+func translatePostgres(code string) error {
+	switch code {
+	case "23505": // unique_violation
+		return ErrConflict
+	case "23502", "23503", "23514", "23P01": // not_null_violation, foreign_key_violation, check_violation, exclusion_violation
+		return ErrConstraint
+	case "40001": // serialization_failure
+		return ErrStale
+	case "40P01": // deadlock_detected
+		return ErrDeadlock
+	default:
+		return nil
+	}
+}
+
+// translateSQLite translates SQLite primary and extended result codes.
+// Reference: https://www.sqlite.org/rescode.html
+func translateSQLite(code, extended int64) error {
+	switch {
+	case extended == 2067, extended == 1555: // SQLITE_CONSTRAINT_UNIQUE, SQLITE_CONSTRAINT_PRIMARYKEY
+		return ErrConflict
+	case code == 19: // SQLITE_CONSTRAINT
+		return ErrConstraint
+	default:
+		return nil
+	}
+}
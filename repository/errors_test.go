@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"reflect"
+	"testing"
+)
+
+func TestTranslateError(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{name: "gorm not found", err: gorm.ErrRecordNotFound, expected: ErrNotFound},
+		{name: "gorm duplicated key", err: gorm.ErrDuplicatedKey, expected: ErrConflict},
+		{name: "mysql duplicate entry", err: &mysql.MySQLError{Number: 1062}, expected: ErrDuplicate},
+		{name: "mysql foreign key", err: &mysql.MySQLError{Number: 1452}, expected: ErrConstraint},
+		{name: "mysql not null", err: &mysql.MySQLError{Number: 1048}, expected: ErrConstraint},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, expected: ErrDeadlock},
+		{name: "postgres unique", err: &pgconn.PgError{Code: "23505"}, expected: ErrConflict},
+		{name: "postgres foreign key", err: &pgconn.PgError{Code: "23503"}, expected: ErrConstraint},
+		{name: "postgres check", err: &pgconn.PgError{Code: "23514"}, expected: ErrConstraint},
+		{name: "postgres serialization", err: &pgconn.PgError{Code: "40001"}, expected: ErrStale},
+		{name: "postgres deadlock", err: &pgconn.PgError{Code: "40P01"}, expected: ErrDeadlock},
+		{name: "sqlite unique", err: sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}, expected: ErrConflict},
+		{name: "sqlite primary key", err: &sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintPrimaryKey}, expected: ErrConflict},
+		{name: "sqlite not null", err: sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintNotNull}, expected: ErrConstraint},
+		{name: "wrapped", err: fmt.Errorf("failed: %w", &pgconn.PgError{Code: "23505"}), expected: ErrConflict},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.ErrorIs(t, translateError(c.err), c.expected)
+		})
+	}
+
+	assert.NoError(t, translateError(nil))
+
+	driverErr := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	translated := translateError(fmt.Errorf("failed: %w", driverErr))
+	var target *mysql.MySQLError
+	assert.True(t, errors.As(translated, &target))
+	assert.Same(t, driverErr, target)
+	assert.NotErrorIs(t, translated, ErrConstraint)
+
+	unknown := errors.New("unknown")
+	assert.Equal(t, unknown, translateError(unknown))
+}
+
+func TestSQLiteCodes(t *testing.T) {
+	// sqliteCodes reads the driver error by reflection, this pins the contract it relies on.
+	typ := reflect.TypeOf(sqlite3.Error{})
+	assert.Equal(t, sqlitePackage, typ.PkgPath())
+	assert.Equal(t, "Error", typ.Name())
+	for _, name := range []string{"Code", "ExtendedCode"} {
+		field, ok := typ.FieldByName(name)
+		if assert.True(t, ok, name) {
+			assert.Equal(t, reflect.Int, field.Type.Kind(), name)
+		}
+	}
+
+	err := sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}
+	for _, e := range []error{err, &err} {
+		code, extended, ok := sqliteCodes(e)
+		assert.True(t, ok)
+		assert.Equal(t, int64(sqlite3.ErrConstraint), code)
+		assert.Equal(t, int64(sqlite3.ErrConstraintUnique), extended)
+	}
+	_, _, ok := sqliteCodes(errors.New("constraint failed"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sync"
+)
+
+// Hooks contains functions called around the write operations of a Repository wrapped using WithHooks. Every
+// hook is optional.
+//
+// Before hooks receive a pointer to the entity about to be written, so they can mutate it, and returning an
+// error aborts the operation before anything is written. After hooks receive the persisted entity, and the error
+// they return is returned by the operation, although the entity was already written. Run the operation in a
+// UnitOfWork to roll it back when an after hook fails.
+type Hooks[E any, K comparable] struct {
+	// BeforeCreate is called before creating an entity, including the entities written by Upsert and UpsertBulk.
+	BeforeCreate func(ctx context.Context, entity *E) error
+	// AfterCreate is called with every entity created or upserted.
+	AfterCreate func(ctx context.Context, entity E) error
+	// BeforeUpdate is called before updating the entity identified by id. Patches are passed as an entity holding
+	// the patched fields, and fields changed by the hook are added to the patch. For UpdateBulk, it's called for
+	// every ID with the same entity, so changes made for an ID apply to all of them.
+	BeforeUpdate func(ctx context.Context, id K, entity *E) error
+	// AfterUpdate is called with every updated entity.
+	AfterUpdate func(ctx context.Context, entity E) error
+	// BeforeRemove is called before removing the entity identified by id, including permanent removals.
+	BeforeRemove func(ctx context.Context, id K) error
+	// AfterRemove is called with every removed entity, including the entities removed permanently.
+	AfterRemove func(ctx context.Context, entity E) error
+}
+
+// hooked is a Repository calling hooks around its write operations.
+type hooked[E any, K comparable] struct {
+	Repository[E, K]
+	hooks Hooks[E, K]
+
+	once   sync.Once
+	schema *schema.Schema
+	err    error
+}
+
+// parse parses the schema of the entity, used to resolve patches. The schema is only parsed once.
+func (r *hooked[E, K]) parse() error {
+	r.once.Do(func() {
+		r.schema, r.err = schema.Parse(new(E), &sync.Map{}, schema.NamingStrategy{})
+	})
+	return r.err
+}
+
+// beforeCreate calls the BeforeCreate hook with every entity. The entities are copied before calling the hook,
+// so the slice passed by the caller isn't mutated.
+func (r *hooked[E, K]) beforeCreate(ctx context.Context, entities []E) ([]E, error) {
+	if r.hooks.BeforeCreate == nil {
+		return entities, nil
+	}
+	out := make([]E, len(entities))
+	copy(out, entities)
+	for i := range out {
+		if err := r.hooks.BeforeCreate(ctx, &out[i]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// after calls hook with every entity, returning the first error.
+func (r *hooked[E, K]) after(ctx context.Context, hook func(ctx context.Context, entity E) error, entities ...E) error {
+	if hook == nil {
+		return nil
+	}
+	for _, entity := range entities {
+		if err := hook(ctx, entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create creates an entity, calling the BeforeCreate and AfterCreate hooks.
+func (r *hooked[E, K]) Create(ctx context.Context, entity E) (E, error) {
+	if r.hooks.BeforeCreate != nil {
+		if err := r.hooks.BeforeCreate(ctx, &entity); err != nil {
+			var zero E
+			return zero, err
+		}
+	}
+	created, err := r.Repository.Create(ctx, entity)
+	if err != nil {
+		return created, err
+	}
+	return created, r.after(ctx, r.hooks.AfterCreate, created)
+}
+
+// CreateBulk creates a set of entities, calling the BeforeCreate and AfterCreate hooks for every entity.
+func (r *hooked[E, K]) CreateBulk(ctx context.Context, entities []E) ([]E, error) {
+	entities, err := r.beforeCreate(ctx, entities)
+	if err != nil {
+		return nil, err
+	}
+	created, err := r.Repository.CreateBulk(ctx, entities)
+	return created, firstError(err, r.after(ctx, r.hooks.AfterCreate, created...))
+}
+
+// Upsert creates or updates an entity, calling the BeforeCreate and AfterCreate hooks.
+func (r *hooked[E, K]) Upsert(ctx context.Context, entity E, opts ...UpsertOption) (E, error) {
+	if r.hooks.BeforeCreate != nil {
+		if err := r.hooks.BeforeCreate(ctx, &entity); err != nil {
+			var zero E
+			return zero, err
+		}
+	}
+	upserted, err := r.Repository.Upsert(ctx, entity, opts...)
+	if err != nil {
+		return upserted, err
+	}
+	return upserted, r.after(ctx, r.hooks.AfterCreate, upserted)
+}
+
+// UpsertBulk creates or updates a set of entities, calling the BeforeCreate and AfterCreate hooks for every
+// entity.
+func (r *hooked[E, K]) UpsertBulk(ctx context.Context, entities []E, opts ...UpsertOption) ([]E, error) {
+	entities, err := r.beforeCreate(ctx, entities)
+	if err != nil {
+		return nil, err
+	}
+	upserted, err := r.Repository.UpsertBulk(ctx, entities, opts...)
+	return upserted, firstError(err, r.after(ctx, r.hooks.AfterCreate, upserted...))
+}
+
+// Update updates an entity, calling the BeforeUpdate and AfterUpdate hooks.
+func (r *hooked[E, K]) Update(ctx context.Context, id K, entity E) (E, error) {
+	if r.hooks.BeforeUpdate != nil {
+		if err := r.hooks.BeforeUpdate(ctx, id, &entity); err != nil {
+			var zero E
+			return zero, err
+		}
+	}
+	updated, err := r.Repository.Update(ctx, id, entity)
+	if err != nil {
+		return updated, err
+	}
+	return updated, r.after(ctx, r.hooks.AfterUpdate, updated)
+}
+
+// Patch updates the fields of an entity listed in patch, calling the BeforeUpdate and AfterUpdate hooks. The
+// patch is resolved into an entity holding the patched fields before calling BeforeUpdate, and the fields changed
+// by the hook are patched too.
+func (r *hooked[E, K]) Patch(ctx context.Context, id K, patch Patch[E]) (E, error) {
+	var zero E
+	if r.hooks.BeforeUpdate != nil {
+		if err := r.parse(); err != nil {
+			return zero, err
+		}
+		mask, err := patch.mask(ctx, r.schema)
+		if err != nil {
+			return zero, err
+		}
+		entity := mask.entity
+		if err := r.hooks.BeforeUpdate(ctx, id, &entity); err != nil {
+			return zero, err
+		}
+		patch = Mask(entity, append(mask.fields, r.changed(ctx, mask.entity, entity, mask.fields)...)...)
+	}
+	patched, err := r.Repository.Patch(ctx, id, patch)
+	if err != nil {
+		return patched, err
+	}
+	return patched, r.after(ctx, r.hooks.AfterUpdate, patched)
+}
+
+// changed returns the names of the fields whose values differ between before and after, excluding the given
+// fields.
+func (r *hooked[E, K]) changed(ctx context.Context, before, after E, exclude []string) []string {
+	excluded := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+	b, a := reflect.ValueOf(&before).Elem(), reflect.ValueOf(&after).Elem()
+	var out []string
+	for _, field := range r.schema.Fields {
+		if field.DBName == "" || excluded[field.Name] {
+			continue
+		}
+		x, _ := field.ValueOf(ctx, b)
+		y, _ := field.ValueOf(ctx, a)
+		if !reflect.DeepEqual(x, y) {
+			out = append(out, field.Name)
+		}
+	}
+	return out
+}
+
+// UpdateBulk updates multiple entities, calling the BeforeUpdate hook for every ID and the AfterUpdate hook for
+// every updated entity.
+func (r *hooked[E, K]) UpdateBulk(ctx context.Context, ids []K, entity E) (Result[E, K], error) {
+	if r.hooks.BeforeUpdate != nil {
+		for _, id := range ids {
+			if err := r.hooks.BeforeUpdate(ctx, id, &entity); err != nil {
+				return Result[E, K]{}, err
+			}
+		}
+	}
+	result, err := r.Repository.UpdateBulk(ctx, ids, entity)
+	return result, firstError(err, r.after(ctx, r.hooks.AfterUpdate, result.Items...))
+}
+
+// remove calls fn to remove the entity identified by id, calling the BeforeRemove and AfterRemove hooks.
+func (r *hooked[E, K]) remove(ctx context.Context, id K, fn func(ctx context.Context, id K) (E, error)) (E, error) {
+	if r.hooks.BeforeRemove != nil {
+		if err := r.hooks.BeforeRemove(ctx, id); err != nil {
+			var zero E
+			return zero, err
+		}
+	}
+	removed, err := fn(ctx, id)
+	if err != nil {
+		return removed, err
+	}
+	return removed, r.after(ctx, r.hooks.AfterRemove, removed)
+}
+
+// removeBulk calls fn to remove the entities identified by ids, calling the BeforeRemove hook for every ID and
+// the AfterRemove hook for every removed entity.
+func (r *hooked[E, K]) removeBulk(ctx context.Context, ids []K, fn func(ctx context.Context, ids []K) (Result[E, K], error)) (Result[E, K], error) {
+	if r.hooks.BeforeRemove != nil {
+		for _, id := range ids {
+			if err := r.hooks.BeforeRemove(ctx, id); err != nil {
+				return Result[E, K]{}, err
+			}
+		}
+	}
+	result, err := fn(ctx, ids)
+	return result, firstError(err, r.after(ctx, r.hooks.AfterRemove, result.Items...))
+}
+
+// Remove removes an entity, calling the BeforeRemove and AfterRemove hooks.
+func (r *hooked[E, K]) Remove(ctx context.Context, id K) (E, error) {
+	return r.remove(ctx, id, r.Repository.Remove)
+}
+
+// RemoveBulk removes a set of entities, calling the BeforeRemove hook for every ID and the AfterRemove hook for
+// every removed entity.
+func (r *hooked[E, K]) RemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	return r.removeBulk(ctx, ids, r.Repository.RemoveBulk)
+}
+
+// ForceRemove permanently removes an entity, calling the BeforeRemove and AfterRemove hooks.
+func (r *hooked[E, K]) ForceRemove(ctx context.Context, id K) (E, error) {
+	return r.remove(ctx, id, r.Repository.ForceRemove)
+}
+
+// ForceRemoveBulk permanently removes a set of entities, calling the BeforeRemove hook for every ID and the
+// AfterRemove hook for every removed entity.
+func (r *hooked[E, K]) ForceRemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	return r.removeBulk(ctx, ids, r.Repository.ForceRemoveBulk)
+}
+
+// WithHooks wraps repository, calling the given hooks around its write operations, except Restore and
+// RestoreBulk. Hooks behave the same regardless of the persistence layer, and multiple sets of hooks can be
+// registered by wrapping a repository several times, in which case the outermost hooks are called first.
+//
+//	users := repository.WithHooks(repository.NewRepositorySQL[User, uint](db), repository.Hooks[User, uint]{
+//		BeforeCreate: func(ctx context.Context, user *User) error {
+//			user.Email = strings.ToLower(user.Email)
+//			return nil
+//		},
+//	})
+func WithHooks[E any, K comparable](repository Repository[E, K], hooks Hooks[E, K]) Repository[E, K] {
+	return &hooked[E, K]{
+		Repository: repository,
+		hooks:      hooks,
+	}
+}
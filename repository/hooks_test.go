@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	suite.Run(t, &HooksTestSuite{backend: func(s *HooksTestSuite) Repository[Test, uint] {
+		return NewMemory[Test, uint]()
+	}})
+	suite.Run(t, &HooksTestSuite{backend: func(s *HooksTestSuite) Repository[Test, uint] {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "hooks_test.db")))
+		s.Require().NoError(err)
+		s.Require().NoError(db.AutoMigrate(&Test{}))
+		s.T().Cleanup(func() {
+			sqlDB, _ := db.DB()
+			_ = sqlDB.Close()
+		})
+		return NewRepositorySQL[Test, uint](db)
+	}})
+}
+
+type HooksTestSuite struct {
+	suite.Suite
+
+	backend    func(s *HooksTestSuite) Repository[Test, uint]
+	calls      []string
+	repository Repository[Test, uint]
+}
+
+func (s *HooksTestSuite) SetupTest() {
+	s.calls = nil
+	s.repository = WithHooks(s.backend(s), Hooks[Test, uint]{
+		BeforeCreate: func(ctx context.Context, entity *Test) error {
+			if entity.FirstName == "" {
+				return errors.New("first name is required")
+			}
+			entity.LastName = strings.ToUpper(entity.LastName)
+			s.calls = append(s.calls, "BeforeCreate "+entity.FirstName)
+			return nil
+		},
+		AfterCreate: func(ctx context.Context, entity Test) error {
+			s.calls = append(s.calls, "AfterCreate "+entity.FirstName)
+			return nil
+		},
+		BeforeUpdate: func(ctx context.Context, id uint, entity *Test) error {
+			if entity.FirstName == "Invalid" {
+				return errors.New("invalid first name")
+			}
+			entity.LastName = strings.ToUpper(entity.LastName)
+			s.calls = append(s.calls, "BeforeUpdate "+entity.FirstName)
+			return nil
+		},
+		AfterUpdate: func(ctx context.Context, entity Test) error {
+			s.calls = append(s.calls, "AfterUpdate "+entity.FirstName)
+			return nil
+		},
+		BeforeRemove: func(ctx context.Context, id uint) error {
+			if id == 1 {
+				return errors.New("cannot remove the first entity")
+			}
+			s.calls = append(s.calls, "BeforeRemove")
+			return nil
+		},
+		AfterRemove: func(ctx context.Context, entity Test) error {
+			s.calls = append(s.calls, "AfterRemove "+entity.FirstName)
+			return nil
+		},
+	})
+}
+
+func (s *HooksTestSuite) createMockData() []Test {
+	entities, err := s.repository.CreateBulk(context.Background(), []Test{
+		{FirstName: "Marcos", LastName: "Huck"},
+		{FirstName: "Andres", LastName: "Huck"},
+	})
+	s.Require().NoError(err)
+	s.calls = nil
+	return entities
+}
+
+func (s *HooksTestSuite) TestCreate() {
+	ctx := context.Background()
+	created, err := s.repository.Create(ctx, Test{FirstName: "Marcos", LastName: "Huck"})
+	s.Require().NoError(err)
+	s.Assert().Equal("HUCK", created.LastName)
+	s.Assert().Equal([]string{"BeforeCreate Marcos", "AfterCreate Marcos"}, s.calls)
+
+	_, err = s.repository.Create(ctx, Test{})
+	s.Assert().EqualError(err, "first name is required")
+
+	entities := []Test{{FirstName: "Andres", LastName: "Huck"}, {}}
+	_, err = s.repository.CreateBulk(ctx, entities)
+	s.Assert().EqualError(err, "first name is required")
+	s.Assert().Equal("Huck", entities[0].LastName)
+
+	list, err := s.repository.List(ctx, Query{})
+	s.Require().NoError(err)
+	s.Assert().Len(list, 1)
+}
+
+func (s *HooksTestSuite) TestUpsert() {
+	entities := s.createMockData()
+	upserted, err := s.repository.UpsertBulk(context.Background(), []Test{{Model: entities[0].Model, FirstName: "Mark", LastName: "Baker"}})
+	s.Require().NoError(err)
+	s.Assert().Equal("BAKER", upserted[0].LastName)
+	s.Assert().Equal([]string{"BeforeCreate Mark", "AfterCreate Mark"}, s.calls)
+}
+
+func (s *HooksTestSuite) TestUpdate() {
+	ctx := context.Background()
+	entities := s.createMockData()
+
+	updated, err := s.repository.Update(ctx, entities[0].ID, Test{FirstName: "Mark", LastName: "Baker"})
+	s.Require().NoError(err)
+	s.Assert().Equal("BAKER", updated.LastName)
+
+	_, err = s.repository.Update(ctx, entities[0].ID, Test{FirstName: "Invalid"})
+	s.Assert().EqualError(err, "invalid first name")
+
+	result, err := s.repository.UpdateBulk(ctx, []uint{entities[0].ID, entities[1].ID}, Test{LastName: "Huck"})
+	s.Require().NoError(err)
+	s.Assert().Len(result.Items, 2)
+	for _, e := range result.Items {
+		s.Assert().Equal("HUCK", e.LastName)
+	}
+	s.Assert().Equal([]string{
+		"BeforeUpdate Mark", "AfterUpdate Mark",
+		"BeforeUpdate ", "BeforeUpdate ", "AfterUpdate Mark", "AfterUpdate Andres",
+	}, s.calls)
+}
+
+func (s *HooksTestSuite) TestPatch() {
+	ctx := context.Background()
+	entities := s.createMockData()
+
+	patched, err := s.repository.Patch(ctx, entities[0].ID, Fields[Test](map[string]interface{}{"first_name": "Mark"}))
+	s.Require().NoError(err)
+	s.Assert().Equal("Mark", patched.FirstName)
+	s.Assert().Equal("HUCK", patched.LastName)
+
+	patched, err = s.repository.Patch(ctx, entities[0].ID, Mask(Test{FirstName: "Mark", LastName: "baker"}, "FirstName"))
+	s.Require().NoError(err)
+	s.Assert().Equal("BAKER", patched.LastName)
+
+	_, err = s.repository.Patch(ctx, entities[0].ID, Fields[Test](map[string]interface{}{"first_name": "Invalid"}))
+	s.Assert().EqualError(err, "invalid first name")
+
+	_, err = s.repository.Patch(ctx, entities[0].ID, Fields[Test](map[string]interface{}{"unknown": 1}))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+	s.Assert().Equal([]string{"BeforeUpdate Mark", "AfterUpdate Mark", "BeforeUpdate Mark", "AfterUpdate Mark"}, s.calls)
+}
+
+func (s *HooksTestSuite) TestRemove() {
+	ctx := context.Background()
+	entities := s.createMockData()
+
+	_, err := s.repository.Remove(ctx, entities[0].ID)
+	s.Assert().EqualError(err, "cannot remove the first entity")
+	_, err = s.repository.RemoveBulk(ctx, []uint{entities[1].ID, entities[0].ID})
+	s.Assert().EqualError(err, "cannot remove the first entity")
+
+	result, err := s.repository.RemoveBulk(ctx, []uint{entities[1].ID, 100})
+	s.Require().NoError(err)
+	s.Assert().Len(result.Items, 1)
+	s.Assert().Equal([]string{"BeforeRemove", "BeforeRemove", "BeforeRemove", "AfterRemove Andres"}, s.calls)
+
+	list, err := s.repository.List(ctx, Query{})
+	s.Require().NoError(err)
+	s.Assert().Len(list, 1)
+}
+
+func (s *HooksTestSuite) TestAfterError() {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+	s.repository = WithHooks(s.repository, Hooks[Test, uint]{
+		AfterCreate: func(ctx context.Context, entity Test) error {
+			return errFailed
+		},
+	})
+	created, err := s.repository.Create(ctx, Test{FirstName: "Marcos"})
+	s.Assert().ErrorIs(err, errFailed)
+	s.Assert().NotZero(created.ID)
+	s.Assert().Equal([]string{"BeforeCreate Marcos", "AfterCreate Marcos"}, s.calls)
+}
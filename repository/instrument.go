@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/gojaguar/jaguar/telemetry"
+	"time"
+)
+
+// instrumentOptions contains the configuration of Instrument.
+type instrumentOptions struct {
+	registry *telemetry.Registry
+	logger   telemetry.Logger
+	tracer   telemetry.Tracer
+}
+
+// InstrumentOption configures the observability signals recorded by Instrument.
+type InstrumentOption func(o *instrumentOptions)
+
+// WithRegistry records the duration of every call, and the errors labelled by kind, in the given registry.
+func WithRegistry(reg *telemetry.Registry) InstrumentOption {
+	return func(o *instrumentOptions) {
+		o.registry = reg
+	}
+}
+
+// WithLogger writes a debug log for every call to the given logger.
+func WithLogger(logger telemetry.Logger) InstrumentOption {
+	return func(o *instrumentOptions) {
+		o.logger = logger
+	}
+}
+
+// WithTracer starts a span for every call using the given tracer.
+func WithTracer(tracer telemetry.Tracer) InstrumentOption {
+	return func(o *instrumentOptions) {
+		o.tracer = tracer
+	}
+}
+
+// instrumented is a Repository recording logs, metrics and traces of every call.
+type instrumented[E any, K comparable] struct {
+	repository Repository[E, K]
+	name       string
+	options    instrumentOptions
+
+	duration *telemetry.Histogram
+	errors   *telemetry.Counter
+}
+
+// observe calls fn, recording the duration and the error of the given method. The ids parameter contains the
+// amount of entities or IDs passed to the method.
+func (r *instrumented[E, K]) observe(ctx context.Context, method string, ids int, fn func(ctx context.Context) error) error {
+	var span telemetry.Span
+	if r.options.tracer != nil {
+		ctx, span = r.options.tracer.Start(ctx, "repository."+method)
+		span.SetAttribute("repository.entity", r.name)
+		span.SetAttribute("repository.ids", ids)
+		defer span.End()
+	}
+
+	start := time.Now()
+	err := fn(ctx)
+	elapsed := time.Since(start)
+
+	kind := errorKind(err)
+	if r.duration != nil {
+		r.duration.Observe(elapsed.Seconds(), r.name, method)
+		if err != nil {
+			r.errors.Inc(r.name, method, kind)
+		}
+	}
+	if span != nil && err != nil {
+		span.SetAttribute("repository.error", kind)
+		span.RecordError(err)
+	}
+	if r.options.logger != nil {
+		keyvals := []interface{}{"entity", r.name, "method", method, "ids", ids, "duration", elapsed}
+		if err != nil {
+			keyvals = append(keyvals, "error", err, "kind", kind)
+		}
+		r.options.logger.Debug("repository call", keyvals...)
+	}
+	return err
+}
+
+// errorKind returns a short name of the given error, used to label metrics. It returns an empty string if err is
+// nil.
+func errorKind(err error) string {
+	kinds := []struct {
+		target error
+		kind   string
+	}{
+		{target: ErrNotFound, kind: "not_found"},
+		{target: ErrConflict, kind: "conflict"},
+		{target: ErrConstraint, kind: "constraint"},
+		{target: ErrStale, kind: "stale"},
+		{target: ErrDeadlock, kind: "deadlock"},
+		{target: ErrInvalidField, kind: "invalid_field"},
+		{target: ErrInvalidCursor, kind: "invalid_cursor"},
+		{target: ErrMissingPrimaryKey, kind: "missing_primary_key"},
+		{target: ErrNotSoftDeletable, kind: "not_soft_deletable"},
+		{target: ErrMissingTenant, kind: "missing_tenant"},
+		{target: ErrTenantMismatch, kind: "tenant_mismatch"},
+		{target: context.Canceled, kind: "canceled"},
+		{target: context.DeadlineExceeded, kind: "deadline_exceeded"},
+	}
+	if err == nil {
+		return ""
+	}
+	var bulk *BulkError
+	if errors.As(err, &bulk) {
+		return "bulk"
+	}
+	for _, k := range kinds {
+		if errors.Is(err, k.target) {
+			return k.kind
+		}
+	}
+	return "unknown"
+}
+
+// Create creates an entity in a persistence layer.
+func (r *instrumented[E, K]) Create(ctx context.Context, entity E) (result E, err error) {
+	err = r.observe(ctx, "Create", 1, func(ctx context.Context) error {
+		result, err = r.repository.Create(ctx, entity)
+		return err
+	})
+	return result, err
+}
+
+// CreateBulk creates a set of entities in a persistence layer.
+func (r *instrumented[E, K]) CreateBulk(ctx context.Context, entities []E) (result []E, err error) {
+	err = r.observe(ctx, "CreateBulk", len(entities), func(ctx context.Context) error {
+		result, err = r.repository.CreateBulk(ctx, entities)
+		return err
+	})
+	return result, err
+}
+
+// Upsert creates an entity, or updates it if it conflicts with an existing one.
+func (r *instrumented[E, K]) Upsert(ctx context.Context, entity E, opts ...UpsertOption) (result E, err error) {
+	err = r.observe(ctx, "Upsert", 1, func(ctx context.Context) error {
+		result, err = r.repository.Upsert(ctx, entity, opts...)
+		return err
+	})
+	return result, err
+}
+
+// UpsertBulk creates a set of entities, updating the ones that conflict with existing entities.
+func (r *instrumented[E, K]) UpsertBulk(ctx context.Context, entities []E, opts ...UpsertOption) (result []E, err error) {
+	err = r.observe(ctx, "UpsertBulk", len(entities), func(ctx context.Context) error {
+		result, err = r.repository.UpsertBulk(ctx, entities, opts...)
+		return err
+	})
+	return result, err
+}
+
+// Get returns an entity from a persistence layer identified by its ID.
+func (r *instrumented[E, K]) Get(ctx context.Context, id K, opts ...ReadOption) (result E, err error) {
+	err = r.observe(ctx, "Get", 1, func(ctx context.Context) error {
+		result, err = r.repository.Get(ctx, id, opts...)
+		return err
+	})
+	return result, err
+}
+
+// Find returns a set of entities from a persistence layer identified by their ID.
+func (r *instrumented[E, K]) Find(ctx context.Context, ids []K, opts ...ReadOption) (result []E, err error) {
+	err = r.observe(ctx, "Find", len(ids), func(ctx context.Context) error {
+		result, err = r.repository.Find(ctx, ids, opts...)
+		return err
+	})
+	return result, err
+}
+
+// FindOrdered returns a set of entities identified by their IDs, in the same order as the given IDs.
+func (r *instrumented[E, K]) FindOrdered(ctx context.Context, ids []K, opts ...ReadOption) (result Result[E, K], err error) {
+	err = r.observe(ctx, "FindOrdered", len(ids), func(ctx context.Context) error {
+		result, err = r.repository.FindOrdered(ctx, ids, opts...)
+		return err
+	})
+	return result, err
+}
+
+// List returns the entities matching the given Query.
+func (r *instrumented[E, K]) List(ctx context.Context, query Query, opts ...ReadOption) (result []E, err error) {
+	err = r.observe(ctx, "List", 0, func(ctx context.Context) error {
+		result, err = r.repository.List(ctx, query, opts...)
+		return err
+	})
+	return result, err
+}
+
+// Paginate returns a page of the entities matching the given Query.
+func (r *instrumented[E, K]) Paginate(ctx context.Context, query Query, pagination Pagination, opts ...ReadOption) (result Page[E], err error) {
+	err = r.observe(ctx, "Paginate", 0, func(ctx context.Context) error {
+		result, err = r.repository.Paginate(ctx, query, pagination, opts...)
+		return err
+	})
+	return result, err
+}
+
+// Each calls fn with every entity matching the given Query.
+func (r *instrumented[E, K]) Each(ctx context.Context, query Query, fn func(entity E) error, opts ...ReadOption) error {
+	return r.observe(ctx, "Each", 0, func(ctx context.Context) error {
+		return r.repository.Each(ctx, query, fn, opts...)
+	})
+}
+
+// Update updates the non-zero fields of an entity and returns the persisted entity.
+func (r *instrumented[E, K]) Update(ctx context.Context, id K, entity E) (result E, err error) {
+	err = r.observe(ctx, "Update", 1, func(ctx context.Context) error {
+		result, err = r.repository.Update(ctx, id, entity)
+		return err
+	})
+	return result, err
+}
+
+// Patch updates exactly the fields listed in the given Patch and returns the persisted entity.
+func (r *instrumented[E, K]) Patch(ctx context.Context, id K, patch Patch[E]) (result E, err error) {
+	err = r.observe(ctx, "Patch", 1, func(ctx context.Context) error {
+		result, err = r.repository.Patch(ctx, id, patch)
+		return err
+	})
+	return result, err
+}
+
+// UpdateBulk updates multiple entities with values of entity.
+func (r *instrumented[E, K]) UpdateBulk(ctx context.Context, ids []K, entity E) (result Result[E, K], err error) {
+	err = r.observe(ctx, "UpdateBulk", len(ids), func(ctx context.Context) error {
+		result, err = r.repository.UpdateBulk(ctx, ids, entity)
+		return err
+	})
+	return result, err
+}
+
+// Remove removes the given id from the persistence layer.
+func (r *instrumented[E, K]) Remove(ctx context.Context, id K) (result E, err error) {
+	err = r.observe(ctx, "Remove", 1, func(ctx context.Context) error {
+		result, err = r.repository.Remove(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+// RemoveBulk removes a set of elements from the persistence layer.
+func (r *instrumented[E, K]) RemoveBulk(ctx context.Context, ids []K) (result Result[E, K], err error) {
+	err = r.observe(ctx, "RemoveBulk", len(ids), func(ctx context.Context) error {
+		result, err = r.repository.RemoveBulk(ctx, ids)
+		return err
+	})
+	return result, err
+}
+
+// Restore restores a soft deleted entity and returns it.
+func (r *instrumented[E, K]) Restore(ctx context.Context, id K) (result E, err error) {
+	err = r.observe(ctx, "Restore", 1, func(ctx context.Context) error {
+		result, err = r.repository.Restore(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+// RestoreBulk restores a set of soft deleted entities.
+func (r *instrumented[E, K]) RestoreBulk(ctx context.Context, ids []K) (result Result[E, K], err error) {
+	err = r.observe(ctx, "RestoreBulk", len(ids), func(ctx context.Context) error {
+		result, err = r.repository.RestoreBulk(ctx, ids)
+		return err
+	})
+	return result, err
+}
+
+// ForceRemove permanently removes an entity, even if it was soft deleted.
+func (r *instrumented[E, K]) ForceRemove(ctx context.Context, id K) (result E, err error) {
+	err = r.observe(ctx, "ForceRemove", 1, func(ctx context.Context) error {
+		result, err = r.repository.ForceRemove(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+// ForceRemoveBulk permanently removes a set of entities, even if they were soft deleted.
+func (r *instrumented[E, K]) ForceRemoveBulk(ctx context.Context, ids []K) (result Result[E, K], err error) {
+	err = r.observe(ctx, "ForceRemoveBulk", len(ids), func(ctx context.Context) error {
+		result, err = r.repository.ForceRemoveBulk(ctx, ids)
+		return err
+	})
+	return result, err
+}
+
+// Instrument wraps repository, recording the calls to every method. The given name identifies the entity in the
+// recorded signals, and the signals are enabled using options:
+//
+//   - WithRegistry records the repository_call_duration_seconds histogram, labelled by entity and method, and the
+//     repository_call_errors_total counter, also labelled by error kind.
+//   - WithLogger writes a debug log with the entity, method, amount of IDs, duration and error of every call.
+//   - WithTracer starts a span named after the method, with the entity and the amount of IDs as attributes.
+//
+// Example:
+//
+//	users := repository.Instrument(repository.NewRepositorySQL[User, uint](db), "users",
+//		repository.WithRegistry(reg),
+//		repository.WithLogger(telemetry.NewLogfmtLogger(os.Stderr)),
+//	)
+func Instrument[E any, K comparable](repository Repository[E, K], name string, opts ...InstrumentOption) Repository[E, K] {
+	r := &instrumented[E, K]{
+		repository: repository,
+		name:       name,
+	}
+	for _, opt := range opts {
+		opt(&r.options)
+	}
+	if r.options.registry != nil {
+		r.duration = r.options.registry.Histogram("repository_call_duration_seconds", "Latency of repository calls.", nil, "entity", "method")
+		r.errors = r.options.registry.Counter("repository_call_errors_total", "Total repository calls returning an error.", "entity", "method", "kind")
+	}
+	return r
+}
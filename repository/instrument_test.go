@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gojaguar/jaguar/telemetry"
+	"github.com/stretchr/testify/suite"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordedSpan struct {
+	name       string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.attributes[key] = value
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordedSpan) End() {
+	s.ended = true
+}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, telemetry.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &recordedSpan{name: name, attributes: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+type recordingLogger struct {
+	entries []string
+}
+
+func (l *recordingLogger) Debug(msg string, keyvals ...interface{}) {
+	entry := msg
+	for _, v := range keyvals[:6] {
+		entry += " " + fmt.Sprint(v)
+	}
+	l.entries = append(l.entries, entry)
+}
+
+func TestInstrument(t *testing.T) {
+	suite.Run(t, new(InstrumentTestSuite))
+}
+
+type InstrumentTestSuite struct {
+	suite.Suite
+
+	registry   *telemetry.Registry
+	tracer     *recordingTracer
+	logger     *recordingLogger
+	repository Repository[Test, uint]
+}
+
+func (s *InstrumentTestSuite) SetupTest() {
+	s.registry = telemetry.NewRegistry()
+	s.tracer = new(recordingTracer)
+	s.logger = new(recordingLogger)
+	s.repository = Instrument(NewMemory[Test, uint](), "tests",
+		WithRegistry(s.registry),
+		WithTracer(s.tracer),
+		WithLogger(s.logger),
+	)
+}
+
+func (s *InstrumentTestSuite) TestMetrics() {
+	ctx := context.Background()
+	_, err := s.repository.CreateBulk(ctx, []Test{{FirstName: "Marcos"}, {FirstName: "Andres"}})
+	s.Require().NoError(err)
+	_, err = s.repository.Get(ctx, 1)
+	s.Require().NoError(err)
+	_, err = s.repository.Get(ctx, 10)
+	s.Require().ErrorIs(err, ErrNotFound)
+
+	var buf bytes.Buffer
+	s.Require().NoError(s.registry.WriteText(&buf))
+	text := buf.String()
+	s.Assert().Contains(text, `repository_call_duration_seconds_count{entity="tests",method="CreateBulk"} 1`)
+	s.Assert().Contains(text, `repository_call_duration_seconds_count{entity="tests",method="Get"} 2`)
+	s.Assert().Contains(text, `repository_call_errors_total{entity="tests",method="Get",kind="not_found"} 1`)
+	s.Assert().NotContains(text, `method="CreateBulk",kind=`)
+}
+
+func (s *InstrumentTestSuite) TestTraces() {
+	ctx := context.Background()
+	_, err := s.repository.Find(ctx, []uint{1, 2, 3})
+	s.Require().NoError(err)
+	_, err = s.repository.Update(ctx, 1, Test{FirstName: "Mark"})
+	s.Require().ErrorIs(err, ErrNotFound)
+
+	s.Require().Len(s.tracer.spans, 2)
+	find := s.tracer.spans[0]
+	s.Assert().Equal("repository.Find", find.name)
+	s.Assert().Equal("tests", find.attributes["repository.entity"])
+	s.Assert().Equal(3, find.attributes["repository.ids"])
+	s.Assert().NoError(find.err)
+	s.Assert().True(find.ended)
+
+	update := s.tracer.spans[1]
+	s.Assert().Equal("repository.Update", update.name)
+	s.Assert().ErrorIs(update.err, ErrNotFound)
+	s.Assert().Equal("not_found", update.attributes["repository.error"])
+	s.Assert().True(update.ended)
+}
+
+func (s *InstrumentTestSuite) TestLogs() {
+	ctx := context.Background()
+	_, err := s.repository.RemoveBulk(ctx, []uint{1, 2})
+	s.Require().NoError(err)
+	err = s.repository.Each(ctx, Query{}, func(entity Test) error {
+		return nil
+	})
+	s.Require().NoError(err)
+
+	s.Assert().Equal([]string{
+		"repository call entity tests method RemoveBulk ids 2",
+		"repository call entity tests method Each ids 0",
+	}, s.logger.entries)
+}
+
+func (s *InstrumentTestSuite) TestLogfmt() {
+	var buf bytes.Buffer
+	s.repository = Instrument(NewMemory[Test, uint](), "tests", WithLogger(telemetry.NewLogfmtLogger(&buf)))
+	_, err := s.repository.Get(context.Background(), 1)
+	s.Require().ErrorIs(err, ErrNotFound)
+	s.Assert().Contains(buf.String(), `level=debug msg="repository call" entity=tests method=Get ids=1 duration=`)
+	s.Assert().True(strings.HasSuffix(buf.String(), "error=\"entity not found: Test 1\" kind=not_found\n"))
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := map[error]string{
+		nil:                                  "",
+		ErrNotFound:                          "not_found",
+		fmt.Errorf("%w: users", ErrConflict): "conflict",
+		ErrInvalidCursor:                     "invalid_cursor",
+		context.Canceled:                     "canceled",
+		&BulkError{Errors: []ItemError{{Err: ErrConflict}}}: "bulk",
+		errors.New("connection refused"):                    "unknown",
+	}
+	for err, kind := range tests {
+		if got := errorKind(err); got != kind {
+			t.Errorf("errorKind(%v) = %q, want %q", err, got, kind)
+		}
+	}
+}
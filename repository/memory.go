@@ -0,0 +1,1093 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory implements Repository storing entities in memory. It's safe for concurrent use, and it's meant to replace
+// SQL in unit tests of code depending on a Repository.
+//
+// Entities are deep copied when they are stored and returned, so callers cannot mutate the stored state. Fields
+// are resolved using the gorm schema of the entity, the same way SQL does: primary keys and unique indexes are
+// enforced, timestamps tracked by gorm are set, and entities with a gorm.DeletedAt field are soft deleted.
+// Associations are stored as part of the entity, and only returned when loaded using a ReadOption.
+type Memory[E any, K comparable] struct {
+	mu      sync.RWMutex
+	options options
+
+	schema    *schema.Schema
+	pk        *schema.Field
+	deletedAt *schema.Field
+	uniques   []uniqueIndex
+	err       error
+
+	generate func() K
+	sequence uint64
+	items    map[K]E
+	// indexes holds the IDs of the stored entities by their index key, for each of the unique indexes.
+	indexes []map[string]K
+	// changes holds the previous version of the entities written since the last commit, so they can be undone.
+	changes []change[E, K]
+}
+
+// uniqueIndex is a set of fields whose values cannot be repeated across entities.
+type uniqueIndex struct {
+	name   string
+	fields []*schema.Field
+}
+
+// change is the version of an entity before a write, used to undo the write.
+type change[E any, K comparable] struct {
+	id      K
+	entity  E
+	existed bool
+}
+
+// parse parses the schema of the entity, resolving its primary key, soft delete field and unique indexes.
+func (m *Memory[E, K]) parse() error {
+	s, err := schema.Parse(new(E), &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	m.schema = s
+	if m.pk, err = primaryKey(s, m.options.primaryKey); err != nil {
+		return err
+	}
+
+	m.deletedAt = softDeleteField(s)
+
+	indexes := s.ParseIndexes()
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	covered := make(map[*schema.Field]bool)
+	for _, name := range names {
+		if index := indexes[name]; index.Class == "UNIQUE" {
+			u := uniqueIndex{name: name}
+			for _, option := range index.Fields {
+				u.fields = append(u.fields, option.Field)
+			}
+			if len(u.fields) == 1 {
+				covered[u.fields[0]] = true
+			}
+			m.uniques = append(m.uniques, u)
+		}
+	}
+	for _, field := range s.Fields {
+		if field.Unique && !covered[field] && field != m.pk {
+			m.uniques = append(m.uniques, uniqueIndex{name: field.DBName, fields: []*schema.Field{field}})
+		}
+	}
+	m.indexes = make([]map[string]K, len(m.uniques))
+	for i := range m.indexes {
+		m.indexes[i] = make(map[string]K)
+	}
+
+	if m.options.idGenerator != nil {
+		generate, ok := m.options.idGenerator.(func() K)
+		if !ok {
+			return fmt.Errorf("id generator %T doesn't return the primary key type %T", m.options.idGenerator, *new(K))
+		}
+		m.generate = generate
+	}
+	return nil
+}
+
+// check returns the error found when parsing the entity schema, or the context error if ctx is done. It also
+// validates the scope held by ctx.
+func (m *Memory[E, K]) check(ctx context.Context) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, err := compileFilter(m.schema, scopeFromContext(ctx, m.schema)); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// inScope reports whether the entity held by v matches the scope held by ctx. The scope is validated by check.
+func (m *Memory[E, K]) inScope(ctx context.Context, v reflect.Value) bool {
+	pred, err := compileFilter(m.schema, scopeFromContext(ctx, m.schema))
+	return err == nil && (pred == nil || pred(ctx, v))
+}
+
+// primaryKeyOf returns the primary key of the entity held by v.
+func (m *Memory[E, K]) primaryKeyOf(ctx context.Context, v reflect.Value) K {
+	return primaryKeyValue[K](ctx, m.pk, v)
+}
+
+// nextID returns a new primary key. Integer keys are generated sequentially unless the repository was configured
+// with WithIDGenerator, other keys are left empty.
+func (m *Memory[E, K]) nextID() K {
+	if m.generate != nil {
+		return m.generate()
+	}
+	var id K
+	t := reflect.TypeOf(id)
+	if t == nil || !isInteger(t.Kind()) {
+		return id
+	}
+	m.sequence++
+	return reflect.ValueOf(m.sequence).Convert(t).Interface().(K)
+}
+
+// track advances the sequence used to generate integer keys past the given key.
+func (m *Memory[E, K]) track(id K) {
+	v := reflect.ValueOf(id)
+	var n uint64
+	switch {
+	case !v.IsValid():
+		return
+	case v.CanInt():
+		if v.Int() < 0 {
+			return
+		}
+		n = uint64(v.Int())
+	case v.CanUint():
+		n = v.Uint()
+	default:
+		return
+	}
+	if n > m.sequence {
+		m.sequence = n
+	}
+}
+
+// isInteger returns true if the given kind is an integer kind.
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// live reports whether the entity held by v isn't soft deleted.
+func (m *Memory[E, K]) live(ctx context.Context, v reflect.Value) bool {
+	if m.deletedAt == nil {
+		return true
+	}
+	value, _ := m.deletedAt.ValueOf(ctx, v)
+	return normalize(value) == nil
+}
+
+// visible reports whether the entity held by v is returned by reads with the given trashed option.
+func (m *Memory[E, K]) visible(ctx context.Context, v reflect.Value, t trashed) bool {
+	switch t {
+	case trashedIncluded:
+		return true
+	case trashedOnly:
+		return !m.live(ctx, v)
+	default:
+		return m.live(ctx, v)
+	}
+}
+
+// lookUp returns the stored entity identified by id if it exists, it isn't soft deleted and it's in the scope of
+// ctx.
+func (m *Memory[E, K]) lookUp(ctx context.Context, id K) (E, error) {
+	return m.lookUpTrashed(ctx, id, trashedExcluded)
+}
+
+// lookUpTrashed returns the stored entity identified by id if it exists, it's visible with the given trashed option
+// and it's in the scope of ctx.
+func (m *Memory[E, K]) lookUpTrashed(ctx context.Context, id K, t trashed) (E, error) {
+	entity, ok := m.items[id]
+	if !ok || !m.visible(ctx, reflect.ValueOf(&entity).Elem(), t) || !m.inScope(ctx, reflect.ValueOf(&entity).Elem()) {
+		var zero E
+		return zero, fmt.Errorf("%w: %s %v", ErrNotFound, m.schema.Name, id)
+	}
+	return entity, nil
+}
+
+// touch sets the timestamps tracked by gorm of the entity held by v. Creation timestamps are only set if they are
+// empty and create is true.
+func (m *Memory[E, K]) touch(ctx context.Context, v reflect.Value, create bool) error {
+	now := time.Now()
+	for _, field := range m.schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		_, zero := field.ValueOf(ctx, v)
+		if (create && field.AutoCreateTime > 0 && zero) || (field.AutoUpdateTime > 0 && (zero || !create)) {
+			if err := field.Set(ctx, v, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// conflict returns ErrConflict if the entity held by v, identified by id, shares the values of a unique index with
+// another stored entity. Null values don't conflict, like in SQL.
+func (m *Memory[E, K]) conflict(ctx context.Context, id K, v reflect.Value) error {
+	for i, u := range m.uniques {
+		key, ok := indexKey(ctx, v, u.fields)
+		if !ok {
+			continue
+		}
+		if other, ok := m.indexes[i][key]; ok && other != id {
+			return fmt.Errorf("%w: %s unique index %s", ErrConflict, m.schema.Name, u.name)
+		}
+	}
+	return nil
+}
+
+// indexKey returns the key of the entity held by v in a unique index made of the given fields. It returns false if
+// any of the values is null.
+func indexKey(ctx context.Context, v reflect.Value, fields []*schema.Field) (string, bool) {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		value, _ := field.ValueOf(ctx, v)
+		switch values[i] = normalize(value); x := values[i].(type) {
+		case nil:
+			return "", false
+		case time.Time:
+			values[i] = x.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return fmt.Sprintf("%#v", values), true
+}
+
+// equal reports whether the given fields of the entity held by v are equal to the given normalized values.
+func (m *Memory[E, K]) equal(ctx context.Context, v reflect.Value, fields []*schema.Field, values []interface{}) bool {
+	for i, field := range fields {
+		value, _ := field.ValueOf(ctx, v)
+		if cmp, ok := compare(normalize(value), values[i]); !ok || cmp != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// put stores entity as the entity identified by id, recording the previous version so the write can be undone.
+// Stored entities are never modified in place, so the recorded version can be shared.
+func (m *Memory[E, K]) put(ctx context.Context, id K, entity E) {
+	previous, existed := m.items[id]
+	m.changes = append(m.changes, change[E, K]{id: id, entity: previous, existed: existed})
+	m.set(ctx, id, entity, true)
+}
+
+// delete deletes the entity identified by id, recording it so the write can be undone.
+func (m *Memory[E, K]) delete(ctx context.Context, id K) {
+	previous, existed := m.items[id]
+	if !existed {
+		return
+	}
+	m.changes = append(m.changes, change[E, K]{id: id, entity: previous, existed: true})
+	var zero E
+	m.set(ctx, id, zero, false)
+}
+
+// set replaces the entity identified by id with entity, or deletes it if exists is false, keeping the unique
+// indexes up to date.
+func (m *Memory[E, K]) set(ctx context.Context, id K, entity E, exists bool) {
+	if previous, ok := m.items[id]; ok {
+		pv := reflect.ValueOf(&previous).Elem()
+		for i, u := range m.uniques {
+			if key, ok := indexKey(ctx, pv, u.fields); ok && m.indexes[i][key] == id {
+				delete(m.indexes[i], key)
+			}
+		}
+		delete(m.items, id)
+	}
+	if !exists {
+		return
+	}
+	m.items[id] = entity
+	v := reflect.ValueOf(&entity).Elem()
+	for i, u := range m.uniques {
+		if key, ok := indexKey(ctx, v, u.fields); ok {
+			m.indexes[i][key] = id
+		}
+	}
+}
+
+// commit keeps the writes applied since the last commit.
+func (m *Memory[E, K]) commit() {
+	m.changes = nil
+}
+
+// rollback undoes the writes applied since the last commit, in reverse order.
+func (m *Memory[E, K]) rollback() {
+	ctx := context.Background()
+	for i := len(m.changes) - 1; i >= 0; i-- {
+		c := m.changes[i]
+		m.set(ctx, c.id, c.entity, c.existed)
+	}
+	m.changes = nil
+}
+
+// lock acquires the lock used by writes.
+func (m *Memory[E, K]) lock() {
+	m.mu.Lock()
+}
+
+// unlock undoes the writes that weren't committed and releases the lock used by writes, so an operation that fails
+// or panics leaves the stored entities unchanged.
+func (m *Memory[E, K]) unlock() {
+	m.rollback()
+	m.mu.Unlock()
+}
+
+// store stores a copy of the entity held by v, after checking its unique indexes. It returns a copy of the stored
+// entity.
+func (m *Memory[E, K]) store(ctx context.Context, id K, v reflect.Value) (E, error) {
+	if err := m.conflict(ctx, id, v); err != nil {
+		var zero E
+		return zero, err
+	}
+	entity := v.Interface().(E)
+	m.put(ctx, id, entity)
+	m.track(id)
+	return deepCopy(entity), nil
+}
+
+// create creates the given entity.
+func (m *Memory[E, K]) create(ctx context.Context, entity E) (E, error) {
+	entity = deepCopy(entity)
+	v := reflect.ValueOf(&entity).Elem()
+	var zero K
+	id := m.primaryKeyOf(ctx, v)
+	if id == zero {
+		if id = m.nextID(); id != zero {
+			if err := m.pk.Set(ctx, v, id); err != nil {
+				var zero E
+				return zero, err
+			}
+		}
+	}
+	if _, ok := m.items[id]; ok {
+		var zero E
+		return zero, fmt.Errorf("%w: %s %v", ErrConflict, m.schema.Name, id)
+	}
+	if err := m.touch(ctx, v, true); err != nil {
+		var zero E
+		return zero, err
+	}
+	return m.store(ctx, id, v)
+}
+
+// upsert creates the given entity, or updates the entity it conflicts with according to the given options.
+func (m *Memory[E, K]) upsert(ctx context.Context, entity E, conflict []*schema.Field, update []*schema.Field, o upsertOptions) (E, error) {
+	entity = deepCopy(entity)
+	v := reflect.ValueOf(&entity).Elem()
+	existing, found := m.lookUpConflict(ctx, v, conflict)
+	if !found {
+		return m.create(ctx, entity)
+	}
+
+	stored := deepCopy(m.items[existing])
+	sv := reflect.ValueOf(&stored).Elem()
+	if !o.nothing {
+		if err := m.touch(ctx, v, true); err != nil {
+			var zero E
+			return zero, err
+		}
+		for _, field := range update {
+			if err := field.Set(ctx, sv, field.ReflectValueOf(ctx, v).Interface()); err != nil {
+				var zero E
+				return zero, err
+			}
+		}
+	}
+	if !m.live(ctx, sv) {
+		var zero E
+		return zero, fmt.Errorf("%w: %s %v", ErrNotFound, m.schema.Name, existing)
+	}
+	if o.nothing {
+		return deepCopy(stored), nil
+	}
+	return m.store(ctx, existing, sv)
+}
+
+// lookUpConflict returns the ID of the stored entity sharing the values of the given fields with the entity held
+// by v. The primary key or a unique index made of the same fields is used when possible, instead of comparing every
+// stored entity.
+func (m *Memory[E, K]) lookUpConflict(ctx context.Context, v reflect.Value, fields []*schema.Field) (K, bool) {
+	if len(fields) == 1 && fields[0] == m.pk {
+		id := m.primaryKeyOf(ctx, v)
+		_, ok := m.items[id]
+		return id, ok
+	}
+	for i, u := range m.uniques {
+		if !sameFields(u.fields, fields) {
+			continue
+		}
+		key, ok := indexKey(ctx, v, fields)
+		if !ok {
+			var zero K
+			return zero, false
+		}
+		id, ok := m.indexes[i][key]
+		return id, ok
+	}
+
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		value, _ := field.ValueOf(ctx, v)
+		if values[i] = normalize(value); values[i] == nil {
+			var zero K
+			return zero, false
+		}
+	}
+	for id, stored := range m.items {
+		if m.equal(ctx, reflect.ValueOf(&stored).Elem(), fields, values) {
+			return id, true
+		}
+	}
+	var zero K
+	return zero, false
+}
+
+// sameFields reports whether a and b contain the same fields, in any order.
+func sameFields(a, b []*schema.Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, field := range a {
+		found := false
+		for _, other := range b {
+			found = found || field == other
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// upsertFields resolves the conflict and updated fields of the given upsert options. Every field but the primary
+// key and the creation timestamps is updated by default, like gorm does.
+func (m *Memory[E, K]) upsertFields(o upsertOptions) ([]*schema.Field, []*schema.Field, error) {
+	conflict := []*schema.Field{m.pk}
+	if len(o.conflict) > 0 {
+		conflict = make([]*schema.Field, len(o.conflict))
+		for i, name := range o.conflict {
+			field, err := lookUpField(m.schema, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			conflict[i] = field
+		}
+	}
+
+	var update []*schema.Field
+	if len(o.update) > 0 {
+		for _, name := range o.update {
+			field, err := lookUpField(m.schema, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			update = append(update, field)
+		}
+	} else {
+		for _, field := range m.schema.Fields {
+			if field.DBName != "" && !field.PrimaryKey && field != m.pk && field.AutoCreateTime == 0 {
+				update = append(update, field)
+			}
+		}
+	}
+	return conflict, update, nil
+}
+
+// update updates the entity identified by id with the non-zero fields of entity.
+func (m *Memory[E, K]) update(ctx context.Context, id K, entity E) (E, error) {
+	stored, err := m.lookUp(ctx, id)
+	if err != nil {
+		return stored, err
+	}
+	entity = deepCopy(entity)
+	v := reflect.ValueOf(&entity).Elem()
+	var zero K
+	if key := m.primaryKeyOf(ctx, v); key != zero && key != id {
+		var zero E
+		return zero, fmt.Errorf("%w: %s %v", ErrNotFound, m.schema.Name, id)
+	}
+
+	stored = deepCopy(stored)
+	sv := reflect.ValueOf(&stored).Elem()
+	for _, field := range m.schema.Fields {
+		if field.DBName == "" || field.PrimaryKey || field == m.pk {
+			continue
+		}
+		if value, isZero := field.ValueOf(ctx, v); !isZero {
+			if err := field.Set(ctx, sv, value); err != nil {
+				var zero E
+				return zero, err
+			}
+		}
+	}
+	if err := m.touch(ctx, sv, false); err != nil {
+		var zero E
+		return zero, err
+	}
+	return m.store(ctx, id, sv)
+}
+
+// patch updates the given columns of the entity identified by id.
+func (m *Memory[E, K]) patch(ctx context.Context, id K, values map[string]interface{}) (E, error) {
+	stored, err := m.lookUp(ctx, id)
+	if err != nil || len(values) == 0 {
+		return deepCopy(stored), err
+	}
+
+	stored = deepCopy(stored)
+	sv := reflect.ValueOf(&stored).Elem()
+	var touched bool
+	for name, value := range values {
+		field := m.schema.LookUpField(name)
+		if err := field.Set(ctx, sv, deepCopy(value)); err != nil {
+			var zero E
+			return zero, err
+		}
+		touched = touched || field.AutoUpdateTime > 0
+	}
+	if !touched {
+		if err := m.touch(ctx, sv, false); err != nil {
+			var zero E
+			return zero, err
+		}
+	}
+	return m.store(ctx, id, sv)
+}
+
+// remove removes the entity identified by id, soft deleting it if the entity has a gorm.DeletedAt field.
+func (m *Memory[E, K]) remove(ctx context.Context, id K) (E, error) {
+	stored, err := m.lookUp(ctx, id)
+	if err != nil {
+		return stored, err
+	}
+	if m.deletedAt == nil {
+		m.delete(ctx, id)
+		return deepCopy(stored), nil
+	}
+	stored = deepCopy(stored)
+	sv := reflect.ValueOf(&stored).Elem()
+	if err := m.deletedAt.Set(ctx, sv, time.Now()); err != nil {
+		var zero E
+		return zero, err
+	}
+	m.put(ctx, id, stored)
+	return deepCopy(stored), nil
+}
+
+// restore restores the soft deleted entity identified by id.
+func (m *Memory[E, K]) restore(ctx context.Context, id K) (E, error) {
+	stored, err := m.lookUpTrashed(ctx, id, trashedIncluded)
+	if err != nil {
+		return stored, err
+	}
+	stored = deepCopy(stored)
+	sv := reflect.ValueOf(&stored).Elem()
+	m.deletedAt.ReflectValueOf(ctx, sv).Set(reflect.Zero(m.deletedAt.FieldType))
+	if err := m.touch(ctx, sv, false); err != nil {
+		var zero E
+		return zero, err
+	}
+	m.put(ctx, id, stored)
+	return deepCopy(stored), nil
+}
+
+// forceRemove permanently removes the entity identified by id, even if it was soft deleted.
+func (m *Memory[E, K]) forceRemove(ctx context.Context, id K) (E, error) {
+	stored, err := m.lookUpTrashed(ctx, id, trashedIncluded)
+	if err != nil {
+		return stored, err
+	}
+	m.delete(ctx, id)
+	return deepCopy(stored), nil
+}
+
+// read returns a copy of the given entity with the associations loaded by the given node.
+func (m *Memory[E, K]) read(ctx context.Context, entity E, associations *associationNode) E {
+	entity = deepCopy(entity)
+	associations.shape(ctx, reflect.ValueOf(&entity).Elem(), m.schema)
+	return entity
+}
+
+// query returns the entities matching the given Query, skipping offset entities and returning at most limit
+// entities. A negative limit returns every entity.
+func (m *Memory[E, K]) query(ctx context.Context, q Query, offset, limit int, associations *associationNode, t trashed) ([]E, error) {
+	matches, err := m.match(ctx, q.Filter, t)
+	if err != nil {
+		return nil, err
+	}
+	sorts, err := resolveSorts(m.schema, q.Sort)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]*schema.Field, len(q.Fields))
+	for i, name := range q.Fields {
+		if fields[i], err = lookUpField(m.schema, name); err != nil {
+			return nil, err
+		}
+	}
+
+	sortEntities(ctx, matches, sorts, m.pk)
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	matches = matches[offset:]
+	if limit >= 0 && limit < len(matches) {
+		matches = matches[:limit]
+	}
+
+	out := make([]E, len(matches))
+	for i, entity := range matches {
+		out[i] = m.read(ctx, entity, associations)
+		if len(fields) > 0 {
+			if out[i], err = project(ctx, out[i], fields); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// match returns the stored entities matching the given Filter and the scope of ctx that are visible with the given
+// trashed option.
+func (m *Memory[E, K]) match(ctx context.Context, f Filter, t trashed) ([]E, error) {
+	pred, err := compileFilter(m.schema, scoped(ctx, m.schema, f))
+	if err != nil {
+		return nil, err
+	}
+	var out []E
+	for _, entity := range m.items {
+		v := reflect.ValueOf(&entity).Elem()
+		if m.visible(ctx, v, t) && (pred == nil || pred(ctx, v)) {
+			out = append(out, entity)
+		}
+	}
+	return out, nil
+}
+
+// find returns the stored entities identified by ids that are visible with the given trashed option, sorted by
+// primary key.
+func (m *Memory[E, K]) find(ctx context.Context, ids []K, associations *associationNode, t trashed) []E {
+	out := make([]E, 0, len(ids))
+	for _, id := range unique(ids) {
+		if entity, err := m.lookUpTrashed(ctx, id, t); err == nil {
+			out = append(out, m.read(ctx, entity, associations))
+		}
+	}
+	sortEntities(ctx, out, nil, m.pk)
+	return out
+}
+
+// reading validates the given read options, returning the associations they load and whether they read soft
+// deleted entities.
+func (m *Memory[E, K]) reading(opts []ReadOption) (*associationNode, trashed, error) {
+	o := newReadOptions(opts)
+	if err := checkTrashed(m.schema, m.deletedAt, o); err != nil {
+		return nil, o.trashed, err
+	}
+	associations, err := resolveAssociations(m.schema, o)
+	return associations, o.trashed, err
+}
+
+// bulk applies fn to every item of a bulk operation. Items are applied atomically unless the repository was
+// configured with WithBestEffort, in which case each item is committed or rolled back independently and the errors
+// of the items that failed are returned in a BulkError. ErrNotFound errors are passed to missing instead, if it's
+// not nil. The writes of a failed atomic operation are undone by unlock.
+func (m *Memory[E, K]) bulk(n int, fn func(i int) error, missing func(i int)) error {
+	if !m.options.bestEffort {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				if missing != nil && errors.Is(err, ErrNotFound) {
+					missing(i)
+					continue
+				}
+				return err
+			}
+		}
+		m.commit()
+		return nil
+	}
+
+	var errs []ItemError
+	for i := 0; i < n; i++ {
+		if err := fn(i); err != nil {
+			m.rollback()
+			if missing != nil && errors.Is(err, ErrNotFound) {
+				missing(i)
+				continue
+			}
+			errs = append(errs, ItemError{Index: i, Err: err})
+			continue
+		}
+		m.commit()
+	}
+	return bulkError(errs)
+}
+
+// bulkResult applies fn to every entity identified by ids using bulk, returning the entities returned by fn and
+// the IDs that didn't match any entity.
+func (m *Memory[E, K]) bulkResult(ctx context.Context, ids []K, fn func(ctx context.Context, id K) (E, error)) (Result[E, K], error) {
+	ids = unique(ids)
+	var result Result[E, K]
+	err := m.bulk(len(ids), func(i int) error {
+		entity, err := fn(ctx, ids[i])
+		if err == nil {
+			result.Items = append(result.Items, entity)
+		}
+		return err
+	}, func(i int) {
+		result.Missing = append(result.Missing, ids[i])
+	})
+	if err != nil && !m.options.bestEffort {
+		return Result[E, K]{}, err
+	}
+	sortEntities(ctx, result.Items, nil, m.pk)
+	return result, err
+}
+
+// Create creates an entity in a persistence layer.
+func (m *Memory[E, K]) Create(ctx context.Context, entity E) (E, error) {
+	if err := m.check(ctx); err != nil {
+		var zero E
+		return zero, err
+	}
+	m.lock()
+	defer m.unlock()
+	out, err := m.create(ctx, entity)
+	if err == nil {
+		m.commit()
+	}
+	return out, err
+}
+
+// CreateBulk creates a set of entities in a persistence layer. Entities are created atomically, unless the
+// repository was configured with WithBestEffort.
+func (m *Memory[E, K]) CreateBulk(ctx context.Context, entities []E) ([]E, error) {
+	if err := m.check(ctx); err != nil {
+		return nil, err
+	}
+	m.lock()
+	defer m.unlock()
+	created := make([]E, 0, len(entities))
+	err := m.bulk(len(entities), func(i int) error {
+		entity, err := m.create(ctx, entities[i])
+		if err == nil {
+			created = append(created, entity)
+		}
+		return err
+	}, nil)
+	if err != nil && !m.options.bestEffort {
+		return nil, err
+	}
+	return created, err
+}
+
+// Upsert creates an entity, or updates it if it conflicts with an existing one. It returns the persisted entity.
+func (m *Memory[E, K]) Upsert(ctx context.Context, entity E, opts ...UpsertOption) (E, error) {
+	out, err := m.UpsertBulk(ctx, []E{entity}, opts...)
+	if err != nil {
+		var zero E
+		return zero, err
+	}
+	return out[0], nil
+}
+
+// UpsertBulk creates a set of entities, updating the ones that conflict with existing entities. It returns the
+// persisted entities in the same order.
+func (m *Memory[E, K]) UpsertBulk(ctx context.Context, entities []E, opts ...UpsertOption) ([]E, error) {
+	if err := m.check(ctx); err != nil {
+		return nil, err
+	}
+	o := newUpsertOptions(opts)
+	conflict, update, err := m.upsertFields(o)
+	if err != nil {
+		return nil, err
+	}
+	m.lock()
+	defer m.unlock()
+	out := make([]E, len(entities))
+	for i, entity := range entities {
+		if out[i], err = m.upsert(ctx, entity, conflict, update, o); err != nil {
+			return nil, err
+		}
+	}
+	m.commit()
+	return out, nil
+}
+
+// Get returns an entity from a persistence layer identified by its ID. It returns ErrNotFound if the entity doesn't exist.
+func (m *Memory[E, K]) Get(ctx context.Context, id K, opts ...ReadOption) (E, error) {
+	var zero E
+	if err := m.check(ctx); err != nil {
+		return zero, err
+	}
+	associations, t, err := m.reading(opts)
+	if err != nil {
+		return zero, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entity, err := m.lookUpTrashed(ctx, id, t)
+	if err != nil {
+		return zero, err
+	}
+	return m.read(ctx, entity, associations), nil
+}
+
+// Find returns a set of entities from a persistence layer identified by their IDs. It returns
+// an empty slice if no records were found.
+func (m *Memory[E, K]) Find(ctx context.Context, ids []K, opts ...ReadOption) ([]E, error) {
+	if err := m.check(ctx); err != nil {
+		return nil, err
+	}
+	associations, t, err := m.reading(opts)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.find(ctx, ids, associations, t), nil
+}
+
+// FindOrdered returns a set of entities identified by their IDs, in the same order as the given IDs. Repeated
+// IDs are only returned once, and the result contains the IDs that didn't match any entity.
+func (m *Memory[E, K]) FindOrdered(ctx context.Context, ids []K, opts ...ReadOption) (Result[E, K], error) {
+	if err := m.check(ctx); err != nil {
+		return Result[E, K]{}, err
+	}
+	associations, t, err := m.reading(opts)
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids = unique(ids)
+	result := Result[E, K]{Items: make([]E, 0, len(ids))}
+	for _, id := range ids {
+		entity, err := m.lookUpTrashed(ctx, id, t)
+		if err != nil {
+			result.Missing = append(result.Missing, id)
+			continue
+		}
+		result.Items = append(result.Items, m.read(ctx, entity, associations))
+	}
+	return result, nil
+}
+
+// List returns the entities matching the given Query. It returns an empty slice if no records were found.
+func (m *Memory[E, K]) List(ctx context.Context, query Query, opts ...ReadOption) ([]E, error) {
+	if err := m.check(ctx); err != nil {
+		return nil, err
+	}
+	associations, t, err := m.reading(opts)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.query(ctx, query, 0, -1, associations, t)
+}
+
+// Paginate returns a page of the entities matching the given Query. It returns ErrInvalidCursor if the
+// pagination cursor cannot be used with the given query.
+func (m *Memory[E, K]) Paginate(ctx context.Context, query Query, pagination Pagination, opts ...ReadOption) (Page[E], error) {
+	if err := m.check(ctx); err != nil {
+		return Page[E]{}, err
+	}
+	associations, t, err := m.reading(opts)
+	if err != nil {
+		return Page[E]{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	fetch := func(q Query, offset, limit int) ([]E, error) {
+		return m.query(ctx, q, offset, limit, associations, t)
+	}
+	count := func(f Filter) (int64, error) {
+		matches, err := m.match(ctx, f, t)
+		return int64(len(matches)), err
+	}
+	return paginate(ctx, m.schema, m.pk, query, pagination, fetch, count)
+}
+
+// Each calls fn with every entity matching the given Query, loading them in batches using keyset pagination so
+// memory usage is bounded. The iteration stops when fn returns an error, which is returned unless it's ErrStop,
+// or when ctx is done.
+func (m *Memory[E, K]) Each(ctx context.Context, query Query, fn func(entity E) error, opts ...ReadOption) error {
+	return each(ctx, m.options.batch(), func(p Pagination) (Page[E], error) {
+		return m.Paginate(ctx, query, p, opts...)
+	}, fn)
+}
+
+// Update updates the non-zero fields of an entity and returns the persisted entity. It returns ErrNotFound if
+// the entity doesn't exist.
+func (m *Memory[E, K]) Update(ctx context.Context, id K, entity E) (E, error) {
+	if err := m.check(ctx); err != nil {
+		var zero E
+		return zero, err
+	}
+	m.lock()
+	defer m.unlock()
+	out, err := m.update(ctx, id, entity)
+	if err == nil {
+		m.commit()
+	}
+	return out, err
+}
+
+// Patch updates exactly the fields listed in the given Patch, including zero values, and returns the persisted
+// entity. It returns ErrNotFound if the entity doesn't exist.
+func (m *Memory[E, K]) Patch(ctx context.Context, id K, patch Patch[E]) (E, error) {
+	var zero E
+	if err := m.check(ctx); err != nil {
+		return zero, err
+	}
+	values, err := patch.columns(ctx, m.schema, m.pk)
+	if err != nil {
+		return zero, err
+	}
+	m.lock()
+	defer m.unlock()
+	out, err := m.patch(ctx, id, values)
+	if err != nil {
+		return zero, err
+	}
+	m.commit()
+	return out, nil
+}
+
+// UpdateBulk updates multiple entities with values of entity. The result contains the persisted entities and
+// the IDs that didn't match any entity. Entities are updated atomically, unless the repository was configured
+// with WithBestEffort.
+func (m *Memory[E, K]) UpdateBulk(ctx context.Context, ids []K, entity E) (Result[E, K], error) {
+	if err := m.check(ctx); err != nil {
+		return Result[E, K]{}, err
+	}
+	m.lock()
+	defer m.unlock()
+	ids = unique(ids)
+	var result Result[E, K]
+	err := m.bulk(len(ids), func(i int) error {
+		updated, err := m.update(ctx, ids[i], entity)
+		if err == nil {
+			result.Items = append(result.Items, updated)
+		}
+		return err
+	}, func(i int) {
+		result.Missing = append(result.Missing, ids[i])
+	})
+	if err != nil && !m.options.bestEffort {
+		return Result[E, K]{}, err
+	}
+	sortEntities(ctx, result.Items, nil, m.pk)
+	return result, err
+}
+
+// Remove removes the given id from the persistence layer.
+func (m *Memory[E, K]) Remove(ctx context.Context, id K) (E, error) {
+	if err := m.check(ctx); err != nil {
+		var zero E
+		return zero, err
+	}
+	m.lock()
+	defer m.unlock()
+	out, err := m.remove(ctx, id)
+	if err == nil {
+		m.commit()
+	}
+	return out, err
+}
+
+// RemoveBulk removes a set of elements from the persistence layer. The result contains the removed entities and
+// the IDs that didn't match any entity. Entities are removed atomically, unless the repository was configured
+// with WithBestEffort.
+func (m *Memory[E, K]) RemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	if err := m.check(ctx); err != nil {
+		return Result[E, K]{}, err
+	}
+	m.lock()
+	defer m.unlock()
+	return m.bulkResult(ctx, ids, m.remove)
+}
+
+// Restore restores a soft deleted entity and returns it. It returns ErrNotFound if the entity doesn't exist, and
+// ErrNotSoftDeletable if the entity cannot be soft deleted.
+func (m *Memory[E, K]) Restore(ctx context.Context, id K) (E, error) {
+	var zero E
+	if err := m.check(ctx); err != nil {
+		return zero, err
+	}
+	if m.deletedAt == nil {
+		return zero, fmt.Errorf("%w: %s", ErrNotSoftDeletable, m.schema.Name)
+	}
+	m.lock()
+	defer m.unlock()
+	out, err := m.restore(ctx, id)
+	if err != nil {
+		return zero, err
+	}
+	m.commit()
+	return out, nil
+}
+
+// RestoreBulk restores a set of soft deleted entities. The result contains the restored entities and the IDs that
+// didn't match any entity. Entities are restored atomically, unless the repository was configured with
+// WithBestEffort.
+func (m *Memory[E, K]) RestoreBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	if err := m.check(ctx); err != nil {
+		return Result[E, K]{}, err
+	}
+	if m.deletedAt == nil {
+		return Result[E, K]{}, fmt.Errorf("%w: %s", ErrNotSoftDeletable, m.schema.Name)
+	}
+	m.lock()
+	defer m.unlock()
+	return m.bulkResult(ctx, ids, m.restore)
+}
+
+// ForceRemove permanently removes an entity, even if it was soft deleted, and returns it. It returns ErrNotFound if
+// the entity doesn't exist.
+func (m *Memory[E, K]) ForceRemove(ctx context.Context, id K) (E, error) {
+	if err := m.check(ctx); err != nil {
+		var zero E
+		return zero, err
+	}
+	m.lock()
+	defer m.unlock()
+	out, err := m.forceRemove(ctx, id)
+	if err == nil {
+		m.commit()
+	}
+	return out, err
+}
+
+// ForceRemoveBulk permanently removes a set of entities, even if they were soft deleted. The result contains the
+// removed entities and the IDs that didn't match any entity. Entities are removed atomically, unless the
+// repository was configured with WithBestEffort.
+func (m *Memory[E, K]) ForceRemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	if err := m.check(ctx); err != nil {
+		return Result[E, K]{}, err
+	}
+	m.lock()
+	defer m.unlock()
+	return m.bulkResult(ctx, ids, m.forceRemove)
+}
+
+// NewMemory initializes a new implementation of Repository storing entities in memory. The primary key is read
+// from the entity schema, unless it's set with the WithPrimaryKey option, and integer primary keys are generated
+// sequentially unless an ID generator is set with WithIDGenerator.
+func NewMemory[E any, K comparable](opts ...Option) Repository[E, K] {
+	m := &Memory[E, K]{
+		options: newOptions(opts),
+		items:   make(map[K]E),
+	}
+	m.err = m.parse()
+	return m
+}
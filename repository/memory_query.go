@@ -0,0 +1,444 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// predicate reports whether the entity held by v matches a Filter.
+type predicate func(ctx context.Context, v reflect.Value) bool
+
+// compileFilter translates the given Filter into a predicate, resolving fields against the given schema. It
+// returns nil if the filter matches every entity, like buildFilter does for SQL.
+func compileFilter(s *schema.Schema, f Filter) (predicate, error) {
+	switch f := f.(type) {
+	case nil:
+		return nil, nil
+	case Condition:
+		return compileCondition(s, f)
+	case Group:
+		preds := make([]predicate, 0, len(f.Filters))
+		for _, child := range f.Filters {
+			pred, err := compileFilter(s, child)
+			if err != nil {
+				return nil, err
+			}
+			if pred != nil {
+				preds = append(preds, pred)
+			}
+		}
+		if len(preds) == 0 {
+			return nil, nil
+		}
+		return func(ctx context.Context, v reflect.Value) bool {
+			for _, pred := range preds {
+				if pred(ctx, v) == f.Or {
+					return f.Or
+				}
+			}
+			return !f.Or
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported filter type %T", f)
+	}
+}
+
+// compileCondition translates the given Condition into a predicate, resolving its field against the given schema.
+// Conditions follow SQL semantics: null fields only match OpIsNull, OpNotNull, and OpEq or OpNeq with a nil value.
+func compileCondition(s *schema.Schema, c Condition) (predicate, error) {
+	field, err := lookUpField(s, c.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	var match func(value interface{}) bool
+	switch c.Operator {
+	case OpEq, OpNeq, OpGt, OpGte, OpLt, OpLte:
+		expected := normalize(c.Value)
+		if expected == nil && (c.Operator == OpEq || c.Operator == OpNeq) {
+			isNull := c.Operator == OpEq
+			return func(ctx context.Context, v reflect.Value) bool {
+				value, _ := field.ValueOf(ctx, v)
+				return (normalize(value) == nil) == isNull
+			}, nil
+		}
+		match = func(value interface{}) bool {
+			cmp, ok := compare(value, expected)
+			if !ok {
+				return false
+			}
+			switch c.Operator {
+			case OpEq:
+				return cmp == 0
+			case OpNeq:
+				return cmp != 0
+			case OpGt:
+				return cmp > 0
+			case OpGte:
+				return cmp >= 0
+			case OpLt:
+				return cmp < 0
+			default:
+				return cmp <= 0
+			}
+		}
+	case OpIn:
+		values, ok := c.Value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operator %s expects a []interface{} value, got %T", c.Operator, c.Value)
+		}
+		expected := make([]interface{}, len(values))
+		for i, value := range values {
+			expected[i] = normalize(value)
+		}
+		match = func(value interface{}) bool {
+			for _, e := range expected {
+				if cmp, ok := compare(value, e); ok && cmp == 0 {
+					return true
+				}
+			}
+			return false
+		}
+	case OpLike:
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("operator %s expects a string value, got %T", c.Operator, c.Value)
+		}
+		re := likePattern(pattern)
+		match = func(value interface{}) bool {
+			s, ok := value.(string)
+			return ok && re.MatchString(s)
+		}
+	case OpIsNull, OpNotNull:
+		isNull := c.Operator == OpIsNull
+		return func(ctx context.Context, v reflect.Value) bool {
+			value, _ := field.ValueOf(ctx, v)
+			return (normalize(value) == nil) == isNull
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", c.Operator)
+	}
+
+	return func(ctx context.Context, v reflect.Value) bool {
+		value, _ := field.ValueOf(ctx, v)
+		value = normalize(value)
+		return value != nil && match(value)
+	}, nil
+}
+
+// likePattern translates an SQL LIKE pattern into a regular expression. Like SQLite and the default MySQL
+// collations, matching is case-insensitive.
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// normalize converts the given value into the representation used to compare values: nil, int64, uint64,
+// float64, string, bool or time.Time. Pointers are dereferenced and driver.Valuer values are resolved, other
+// values are returned as they are.
+func normalize(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		resolved, err := valuer.Value()
+		if err != nil {
+			return value
+		}
+		if _, ok := resolved.(driver.Valuer); ok {
+			return resolved
+		}
+		return normalize(resolved)
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+	}
+	return v.Interface()
+}
+
+// compare compares two normalized values, returning -1, 0 or 1. It returns false if the values cannot be compared.
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return compareOrdered(x, y), true
+		case uint64:
+			if x < 0 {
+				return -1, true
+			}
+			return compareOrdered(uint64(x), y), true
+		case float64:
+			return compareOrdered(float64(x), y), true
+		}
+	case uint64:
+		switch y := b.(type) {
+		case uint64:
+			return compareOrdered(x, y), true
+		case int64:
+			if y < 0 {
+				return 1, true
+			}
+			return compareOrdered(x, uint64(y)), true
+		case float64:
+			return compareOrdered(float64(x), y), true
+		}
+	case float64:
+		switch y := b.(type) {
+		case float64:
+			return compareOrdered(x, y), true
+		case int64:
+			return compareOrdered(x, float64(y)), true
+		case uint64:
+			return compareOrdered(x, float64(y)), true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return compareOrdered(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case y:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	default:
+		if reflect.DeepEqual(a, b) {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// compareOrdered compares two ordered values, returning -1, 0 or 1.
+func compareOrdered[T int64 | uint64 | float64 | string](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sortField is a Sort resolved against an entity schema.
+type sortField struct {
+	field *schema.Field
+	desc  bool
+}
+
+// resolveSorts resolves the given sort fields against the given schema.
+func resolveSorts(s *schema.Schema, sorts []Sort) ([]sortField, error) {
+	out := make([]sortField, len(sorts))
+	for i, sort := range sorts {
+		field, err := lookUpField(s, sort.Field)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = sortField{field: field, desc: sort.Desc}
+	}
+	return out, nil
+}
+
+// sortEntities sorts the given entities by the given sort fields, using the primary key as tiebreaker. Null values
+// are sorted first in ascending order, like SQLite and MySQL do.
+func sortEntities[E any](ctx context.Context, entities []E, sorts []sortField, pk *schema.Field) {
+	sorts = append(sorts, sortField{field: pk})
+	sort.SliceStable(entities, func(i, j int) bool {
+		a, b := reflect.ValueOf(&entities[i]).Elem(), reflect.ValueOf(&entities[j]).Elem()
+		for _, s := range sorts {
+			x, _ := s.field.ValueOf(ctx, a)
+			y, _ := s.field.ValueOf(ctx, b)
+			cmp := compareNullable(normalize(x), normalize(y))
+			if cmp == 0 {
+				continue
+			}
+			if s.desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// compareNullable compares two normalized values, sorting nil values first.
+func compareNullable(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	cmp, _ := compare(a, b)
+	return cmp
+}
+
+// project returns a copy of the given entity with only the given fields populated.
+func project[E any](ctx context.Context, entity E, fields []*schema.Field) (E, error) {
+	var out E
+	src, dst := reflect.ValueOf(&entity).Elem(), reflect.ValueOf(&out).Elem()
+	for _, field := range fields {
+		if err := field.Set(ctx, dst, field.ReflectValueOf(ctx, src).Interface()); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// associationNode describes which associations of an entity are loaded, and which of their entities.
+type associationNode struct {
+	filters  []predicate
+	children map[string]*associationNode
+}
+
+// resolveAssociations validates the associations requested by the given read options against the given schema,
+// returning the tree of loaded associations.
+func resolveAssociations(s *schema.Schema, o readOptions) (*associationNode, error) {
+	root := &associationNode{children: make(map[string]*associationNode)}
+	add := func(a association, join bool) error {
+		rels, err := lookUpRelationship(s, a.path)
+		if err != nil {
+			return err
+		}
+		node := root
+		for _, rel := range rels {
+			if join && rel.Type != schema.HasOne && rel.Type != schema.BelongsTo {
+				return fmt.Errorf("%w: %s cannot be joined, only has one and belongs to associations can", ErrInvalidField, a.path)
+			}
+			child, ok := node.children[rel.Name]
+			if !ok {
+				child = &associationNode{children: make(map[string]*associationNode)}
+				node.children[rel.Name] = child
+			}
+			node = child
+		}
+		pred, err := compileFilter(rels[len(rels)-1].FieldSchema, a.filter)
+		if err != nil {
+			return err
+		}
+		if pred != nil {
+			node.filters = append(node.filters, pred)
+		}
+		return nil
+	}
+	for _, a := range o.preloads {
+		if err := add(a, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range o.joins {
+		if err := add(a, true); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// shape clears the associations of the entity held by v that are not loaded by the given node, and removes the
+// associated entities that don't match its filters. v must be settable.
+func (n *associationNode) shape(ctx context.Context, v reflect.Value, s *schema.Schema) {
+	for name, rel := range s.Relationships.Relations {
+		if rel.Field.Schema != s {
+			// gorm registers relationships of other schemas referencing this one, they have no field here.
+			continue
+		}
+		fv := rel.Field.ReflectValueOf(ctx, v)
+		child, ok := n.children[name]
+		if !ok {
+			fv.Set(reflect.Zero(fv.Type()))
+			continue
+		}
+		switch fv.Kind() {
+		case reflect.Slice:
+			kept := reflect.MakeSlice(fv.Type(), 0, fv.Len())
+			for i := 0; i < fv.Len(); i++ {
+				if elem := reflect.Indirect(fv.Index(i)); elem.IsValid() && child.matches(ctx, elem) {
+					child.shape(ctx, elem, rel.FieldSchema)
+					kept = reflect.Append(kept, fv.Index(i))
+				}
+			}
+			if !fv.IsNil() {
+				fv.Set(kept)
+			}
+		default:
+			elem := reflect.Indirect(fv)
+			if !elem.IsValid() {
+				continue
+			}
+			if !child.matches(ctx, elem) {
+				fv.Set(reflect.Zero(fv.Type()))
+				continue
+			}
+			child.shape(ctx, elem, rel.FieldSchema)
+		}
+	}
+}
+
+// matches reports whether the associated entity held by v matches the filters of the node.
+func (n *associationNode) matches(ctx context.Context, v reflect.Value) bool {
+	for _, pred := range n.filters {
+		if !pred(ctx, v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,461 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+)
+
+type Tag struct {
+	Name        string `gorm:"primaryKey"`
+	Data        []byte
+	Description *string
+}
+
+func TestMemory(t *testing.T) {
+	suite.Run(t, new(MemoryTestSuite))
+}
+
+type MemoryTestSuite struct {
+	suite.Suite
+
+	repository Repository[Test, uint]
+}
+
+func (s *MemoryTestSuite) SetupTest() {
+	s.repository = NewMemory[Test, uint]()
+}
+
+func (s *MemoryTestSuite) createMockData() {
+	_, err := s.repository.CreateBulk(context.Background(), []Test{
+		{FirstName: "Marcos", LastName: "Huck"},
+		{FirstName: "Andres", LastName: "Huck"},
+		{FirstName: "Andrew", LastName: "Baker"},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *MemoryTestSuite) TestCreate() {
+	ctx := context.Background()
+	created, err := s.repository.Create(ctx, Test{FirstName: "Marcos"})
+	s.Require().NoError(err)
+	s.Assert().Equal(uint(1), created.ID)
+	s.Assert().False(created.CreatedAt.IsZero())
+	s.Assert().False(created.UpdatedAt.IsZero())
+
+	created, err = s.repository.Create(ctx, Test{Model: gorm.Model{ID: 10}})
+	s.Require().NoError(err)
+	s.Assert().Equal(uint(10), created.ID)
+
+	created, err = s.repository.Create(ctx, Test{})
+	s.Require().NoError(err)
+	s.Assert().Equal(uint(11), created.ID)
+
+	_, err = s.repository.Create(ctx, Test{Model: gorm.Model{ID: 1}})
+	s.Assert().ErrorIs(err, ErrConflict)
+}
+
+func (s *MemoryTestSuite) TestCreate_Unique() {
+	ctx := context.Background()
+	members := NewMemory[Member, uint]()
+	_, err := members.Create(ctx, Member{Email: "marcos@example.com"})
+	s.Require().NoError(err)
+
+	_, err = members.Create(ctx, Member{Email: "marcos@example.com"})
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	_, err = members.CreateBulk(ctx, []Member{{Email: "andres@example.com"}, {Email: "andres@example.com"}})
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	list, err := members.List(ctx, Query{})
+	s.Require().NoError(err)
+	s.Assert().Len(list, 1)
+}
+
+func (s *MemoryTestSuite) TestRollback() {
+	ctx := context.Background()
+	members := NewMemory[Member, uint]()
+	_, err := members.CreateBulk(ctx, []Member{{Email: "marcos@example.com"}, {Email: "andres@example.com"}})
+	s.Require().NoError(err)
+
+	_, err = members.UpsertBulk(ctx, []Member{
+		{ID: 1, Email: "lucia@example.com"},
+		{Email: "julia@example.com"},
+		{ID: 2, Email: "julia@example.com"},
+	})
+	s.Assert().ErrorIs(err, ErrConflict)
+	list, err := members.List(ctx, Query{}.OrderBy(Asc("id")))
+	s.Require().NoError(err)
+	s.Require().Len(list, 2)
+	s.Assert().Equal("marcos@example.com", list[0].Email)
+
+	_, err = members.Create(ctx, Member{Email: "lucia@example.com"})
+	s.Require().NoError(err)
+	_, err = members.Create(ctx, Member{Email: "marcos@example.com"})
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	_, err = members.Patch(ctx, 1, Fields[Member](map[string]interface{}{"id": 10}))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+	_, err = members.Update(ctx, 2, Member{Email: "marcos@example.com"})
+	s.Assert().ErrorIs(err, ErrConflict)
+	_, err = members.Remove(ctx, 1)
+	s.Require().NoError(err)
+	updated, err := members.Update(ctx, 2, Member{Email: "marcos@example.com"})
+	s.Require().NoError(err)
+	s.Assert().Equal("marcos@example.com", updated.Email)
+	_, err = members.Create(ctx, Member{Email: "andres@example.com"})
+	s.Assert().NoError(err)
+}
+
+func (s *MemoryTestSuite) TestGet() {
+	s.createMockData()
+	result, err := s.repository.Get(context.Background(), 2)
+	s.Require().NoError(err)
+	s.Assert().Equal("Andres", result.FirstName)
+
+	_, err = s.repository.Get(context.Background(), 10)
+	s.Assert().ErrorIs(err, ErrNotFound)
+}
+
+func (s *MemoryTestSuite) TestDeepCopy() {
+	ctx := context.Background()
+	tags := NewMemory[Tag, string]()
+	description := "language"
+	tag := Tag{Name: "go", Data: []byte("gopher"), Description: &description}
+	_, err := tags.Create(ctx, tag)
+	s.Require().NoError(err)
+
+	tag.Data[0] = 'G'
+	description = "changed"
+
+	stored, err := tags.Get(ctx, "go")
+	s.Require().NoError(err)
+	s.Assert().Equal("gopher", string(stored.Data))
+	s.Assert().Equal("language", *stored.Description)
+
+	stored.Data[0] = 'G'
+	*stored.Description = "changed"
+	stored, err = tags.Get(ctx, "go")
+	s.Require().NoError(err)
+	s.Assert().Equal("gopher", string(stored.Data))
+	s.Assert().Equal("language", *stored.Description)
+}
+
+func (s *MemoryTestSuite) TestIDGenerator() {
+	var n int
+	tags := NewMemory[Tag, string](WithIDGenerator(func() string {
+		n++
+		return fmt.Sprintf("tag-%d", n)
+	}))
+	created, err := tags.Create(context.Background(), Tag{})
+	s.Require().NoError(err)
+	s.Assert().Equal("tag-1", created.Name)
+
+	created, err = tags.Create(context.Background(), Tag{Name: "go"})
+	s.Require().NoError(err)
+	s.Assert().Equal("go", created.Name)
+
+	_, err = NewMemory[Tag, string](WithIDGenerator(func() int { return 1 })).Create(context.Background(), Tag{})
+	s.Assert().Error(err)
+}
+
+func (s *MemoryTestSuite) TestUpdate() {
+	s.createMockData()
+	ctx := context.Background()
+	result, err := s.repository.Update(ctx, 1, Test{FirstName: "Changed"})
+	s.Require().NoError(err)
+	s.Assert().Equal("Changed", result.FirstName)
+	s.Assert().Equal("Huck", result.LastName)
+
+	_, err = s.repository.Update(ctx, 10, Test{FirstName: "Changed"})
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	bulk, err := s.repository.UpdateBulk(ctx, []uint{3, 2, 10}, Test{LastName: "Changed"})
+	s.Require().NoError(err)
+	s.Require().Len(bulk.Items, 2)
+	s.Assert().Equal(uint(2), bulk.Items[0].ID)
+	s.Assert().Equal("Changed", bulk.Items[1].LastName)
+	s.Assert().Equal([]uint{10}, bulk.Missing)
+}
+
+func (s *MemoryTestSuite) TestPatch() {
+	s.createMockData()
+	ctx := context.Background()
+	result, err := s.repository.Patch(ctx, 1, Mask(Test{}, "LastName"))
+	s.Require().NoError(err)
+	s.Assert().Equal("Marcos", result.FirstName)
+	s.Assert().Empty(result.LastName)
+
+	result, err = s.repository.Patch(ctx, 2, Fields[Test](map[string]interface{}{"first_name": ""}))
+	s.Require().NoError(err)
+	s.Assert().Empty(result.FirstName)
+
+	_, err = s.repository.Patch(ctx, 10, Mask(Test{}, "LastName"))
+	s.Assert().ErrorIs(err, ErrNotFound)
+}
+
+func (s *MemoryTestSuite) TestUpsert() {
+	s.createMockData()
+	ctx := context.Background()
+	result, err := s.repository.Upsert(ctx, Test{Model: gorm.Model{ID: 2}, FirstName: "Andy"}, DoUpdate("first_name"))
+	s.Require().NoError(err)
+	s.Assert().Equal("Andy", result.FirstName)
+	s.Assert().Equal("Huck", result.LastName)
+
+	result, err = s.repository.Upsert(ctx, Test{Model: gorm.Model{ID: 3}, FirstName: "Unknown"}, DoNothing())
+	s.Require().NoError(err)
+	s.Assert().Equal("Andrew", result.FirstName)
+
+	members := NewMemory[Member, uint]()
+	_, err = members.Create(ctx, Member{Email: "marcos@example.com", Name: "Marcos"})
+	s.Require().NoError(err)
+	upserted, err := members.UpsertBulk(ctx, []Member{
+		{Email: "andres@example.com", Name: "Andres"},
+		{Email: "marcos@example.com", Name: "Marcos Huck"},
+	}, OnConflict("Email"))
+	s.Require().NoError(err)
+	s.Require().Len(upserted, 2)
+	s.Assert().Equal(uint(2), upserted[0].ID)
+	s.Assert().Equal(uint(1), upserted[1].ID)
+	s.Assert().Equal("Marcos Huck", upserted[1].Name)
+}
+
+func (s *MemoryTestSuite) TestRestore() {
+	s.createMockData()
+	ctx := context.Background()
+	_, err := s.repository.RemoveBulk(ctx, []uint{1, 2})
+	s.Require().NoError(err)
+
+	trashed, err := s.repository.List(ctx, Query{}, OnlyTrashed())
+	s.Require().NoError(err)
+	s.Assert().Len(trashed, 2)
+	all, err := s.repository.Find(ctx, []uint{1, 2, 3}, WithTrashed())
+	s.Require().NoError(err)
+	s.Assert().Len(all, 3)
+	got, err := s.repository.Get(ctx, 1, WithTrashed())
+	s.Require().NoError(err)
+	s.Assert().True(got.DeletedAt.Valid)
+
+	restored, err := s.repository.Restore(ctx, 1)
+	s.Require().NoError(err)
+	s.Assert().False(restored.DeletedAt.Valid)
+	s.Assert().Equal("Marcos", restored.FirstName)
+
+	result, err := s.repository.RestoreBulk(ctx, []uint{2, 3, 4})
+	s.Require().NoError(err)
+	s.Assert().Len(result.Items, 2)
+	s.Assert().Equal([]uint{4}, result.Missing)
+
+	_, err = s.repository.Restore(ctx, 4)
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	list, err := s.repository.List(ctx, Query{})
+	s.Require().NoError(err)
+	s.Assert().Len(list, 3)
+}
+
+func (s *MemoryTestSuite) TestForceRemove() {
+	s.createMockData()
+	ctx := context.Background()
+	_, err := s.repository.Remove(ctx, 1)
+	s.Require().NoError(err)
+
+	removed, err := s.repository.ForceRemove(ctx, 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("Marcos", removed.FirstName)
+	_, err = s.repository.Get(ctx, 1, WithTrashed())
+	s.Assert().ErrorIs(err, ErrNotFound)
+	_, err = s.repository.ForceRemove(ctx, 1)
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	result, err := s.repository.ForceRemoveBulk(ctx, []uint{2, 3, 4})
+	s.Require().NoError(err)
+	s.Assert().Len(result.Items, 2)
+	s.Assert().Equal([]uint{4}, result.Missing)
+
+	all, err := s.repository.List(ctx, Query{}, WithTrashed())
+	s.Require().NoError(err)
+	s.Assert().Empty(all)
+}
+
+func (s *MemoryTestSuite) TestNotSoftDeletable() {
+	ctx := context.Background()
+	members := NewMemory[Member, uint]()
+	created, err := members.Create(ctx, Member{Email: "marcos@example.com"})
+	s.Require().NoError(err)
+
+	_, err = members.Restore(ctx, created.ID)
+	s.Assert().ErrorIs(err, ErrNotSoftDeletable)
+	_, err = members.RestoreBulk(ctx, []uint{created.ID})
+	s.Assert().ErrorIs(err, ErrNotSoftDeletable)
+	_, err = members.List(ctx, Query{}, WithTrashed())
+	s.Assert().ErrorIs(err, ErrNotSoftDeletable)
+
+	removed, err := members.ForceRemove(ctx, created.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(created.ID, removed.ID)
+}
+
+func (s *MemoryTestSuite) TestRemove() {
+	s.createMockData()
+	ctx := context.Background()
+	removed, err := s.repository.Remove(ctx, 1)
+	s.Require().NoError(err)
+	s.Assert().True(removed.DeletedAt.Valid)
+
+	_, err = s.repository.Get(ctx, 1)
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	_, err = s.repository.Remove(ctx, 1)
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	_, err = s.repository.Create(ctx, Test{Model: gorm.Model{ID: 1}})
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	bulk, err := s.repository.RemoveBulk(ctx, []uint{1, 2, 3})
+	s.Require().NoError(err)
+	s.Assert().Len(bulk.Items, 2)
+	s.Assert().Equal([]uint{1}, bulk.Missing)
+
+	tags := NewMemory[Tag, string]()
+	_, err = tags.Create(ctx, Tag{Name: "go"})
+	s.Require().NoError(err)
+	_, err = tags.Remove(ctx, "go")
+	s.Require().NoError(err)
+	_, err = tags.Create(ctx, Tag{Name: "go"})
+	s.Assert().NoError(err)
+}
+
+func (s *MemoryTestSuite) TestBulk_BestEffort() {
+	s.createMockData()
+	repository := NewMemory[Test, uint](WithBestEffort())
+	created, err := repository.CreateBulk(context.Background(), []Test{
+		{FirstName: "John"},
+		{Model: gorm.Model{ID: 1}, FirstName: "Conflict"},
+	})
+	s.Assert().ErrorIs(err, ErrConflict)
+	s.Assert().Len(created, 1)
+}
+
+func (s *MemoryTestSuite) TestList() {
+	s.createMockData()
+	ctx := context.Background()
+	list, err := s.repository.List(ctx, NewQuery(Eq("last_name", "Huck")).OrderBy(Asc("FirstName")))
+	s.Require().NoError(err)
+	s.Require().Len(list, 2)
+	s.Assert().Equal("Andres", list[0].FirstName)
+	s.Assert().Equal("Marcos", list[1].FirstName)
+
+	list, err = s.repository.List(ctx, NewQuery(Or(Like("first_name", "and%"), In("id", []uint{1}))).OrderBy(Desc("id")))
+	s.Require().NoError(err)
+	s.Require().Len(list, 3)
+	s.Assert().Equal("Andrew", list[0].FirstName)
+
+	list, err = s.repository.List(ctx, NewQuery(Range("id", 2, 3), Neq("first_name", "Andrew"), IsNull("deleted_at")))
+	s.Require().NoError(err)
+	s.Require().Len(list, 1)
+	s.Assert().Equal("Andres", list[0].FirstName)
+
+	list, err = s.repository.List(ctx, Query{}.Select("first_name").OrderBy(Asc("id")))
+	s.Require().NoError(err)
+	s.Require().Len(list, 3)
+	s.Assert().Zero(list[0].ID)
+	s.Assert().Equal("Marcos", list[0].FirstName)
+	s.Assert().Empty(list[0].LastName)
+
+	_, err = s.repository.List(ctx, NewQuery(Eq("unknown", 1)))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+}
+
+func (s *MemoryTestSuite) TestPaginate() {
+	s.createMockData()
+	ctx := context.Background()
+	q := Query{}.OrderBy(Asc("last_name"))
+
+	page, err := s.repository.Paginate(ctx, q, Pagination{Size: 2, Keyset: true, Total: true})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 2)
+	s.Assert().Equal("Andrew", page.Items[0].FirstName)
+	s.Assert().Equal(int64(3), *page.Total)
+
+	page, err = s.repository.Paginate(ctx, q, Pagination{Size: 2, Keyset: true, Cursor: page.Next})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 1)
+	s.Assert().Equal("Andres", page.Items[0].FirstName)
+	s.Assert().Empty(page.Next)
+
+	var ids []uint
+	err = s.repository.Each(ctx, Query{}, func(entity Test) error {
+		ids = append(ids, entity.ID)
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Assert().Equal([]uint{1, 2, 3}, ids)
+}
+
+func (s *MemoryTestSuite) TestFind() {
+	s.createMockData()
+	list, err := s.repository.Find(context.Background(), []uint{3, 1, 10})
+	s.Require().NoError(err)
+	s.Assert().Len(list, 2)
+
+	result, err := s.repository.FindOrdered(context.Background(), []uint{3, 10, 1, 3})
+	s.Require().NoError(err)
+	s.Require().Len(result.Items, 2)
+	s.Assert().Equal(uint(3), result.Items[0].ID)
+	s.Assert().Equal(uint(1), result.Items[1].ID)
+	s.Assert().Equal([]uint{10}, result.Missing)
+}
+
+func (s *MemoryTestSuite) TestRead_Associations() {
+	ctx := context.Background()
+	authors := NewMemory[Author, uint]()
+	_, err := authors.Create(ctx, Author{
+		Name:    "Ursula",
+		Profile: AuthorProfile{Bio: "Earthsea"},
+		Books:   []Book{{Title: "A Wizard of Earthsea", Published: true}, {Title: "Draft"}},
+	})
+	s.Require().NoError(err)
+
+	author, err := authors.Get(ctx, 1)
+	s.Require().NoError(err)
+	s.Assert().Empty(author.Books)
+	s.Assert().Zero(author.Profile)
+
+	author, err = authors.Get(ctx, 1, WithPreloadFilter("Books", Eq("published", true)), WithJoin("Profile"))
+	s.Require().NoError(err)
+	s.Require().Len(author.Books, 1)
+	s.Assert().Equal("A Wizard of Earthsea", author.Books[0].Title)
+	s.Assert().Equal("Earthsea", author.Profile.Bio)
+
+	_, err = authors.Get(ctx, 1, WithPreload("Bookz"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+
+	_, err = authors.Get(ctx, 1, WithJoin("Books"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+}
+
+func (s *MemoryTestSuite) TestConcurrency() {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created, err := s.repository.Create(ctx, Test{FirstName: "Marcos"})
+			s.Assert().NoError(err)
+			_, err = s.repository.Get(ctx, created.ID)
+			s.Assert().NoError(err)
+			_, err = s.repository.List(ctx, Query{})
+			s.Assert().NoError(err)
+		}()
+	}
+	wg.Wait()
+
+	list, err := s.repository.List(ctx, Query{})
+	s.Require().NoError(err)
+	s.Assert().Len(list, 50)
+}
@@ -0,0 +1,75 @@
+package repository
+
+// DefaultBatchSize is the amount of entities written per statement by bulk operations, unless it's set with the
+// WithBatchSize option.
+const DefaultBatchSize = 100
+
+// options contains the configuration of a repository.
+type options struct {
+	// primaryKey contains the name of the primary key field or column. If empty, the primary key is read from
+	// the entity schema.
+	primaryKey string
+	// batchSize contains the amount of entities written per statement by bulk operations.
+	batchSize int
+	// bestEffort determines whether bulk operations process entities independently instead of atomically.
+	bestEffort bool
+	// idGenerator contains the function generating primary keys, a func() K.
+	idGenerator interface{}
+}
+
+// batch returns the batch size used by bulk operations.
+func (o options) batch() int {
+	if o.batchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return o.batchSize
+}
+
+// Option configures a repository.
+type Option func(o *options)
+
+// WithPrimaryKey sets the field used as primary key, it accepts either a struct field name or a column name.
+// It's used for entities whose primary key cannot be read from the gorm schema, such as entities with
+// composite primary keys.
+func WithPrimaryKey(field string) Option {
+	return func(o *options) {
+		o.primaryKey = field
+	}
+}
+
+// WithBatchSize sets the amount of entities written per statement by bulk operations. Large inputs are split in
+// chunks of this size to stay under the parameter limits of the database, while still running in a single
+// transaction.
+func WithBatchSize(size int) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// WithBestEffort makes bulk operations process every entity independently instead of atomically. Entities that
+// fail don't prevent the others from being written, and their errors are returned in a BulkError along with the
+// entities that succeeded.
+func WithBestEffort() Option {
+	return func(o *options) {
+		o.bestEffort = true
+	}
+}
+
+// WithIDGenerator sets the function generating the primary keys of entities created without one. It's used by
+// Memory, whose integer primary keys are generated sequentially by default, while SQL relies on the database.
+//
+//	repository.NewMemory[User, string](repository.WithIDGenerator(uuid.NewString))
+func WithIDGenerator[K comparable](generate func() K) Option {
+	return func(o *options) {
+		o.idGenerator = generate
+	}
+}
+
+// newOptions returns the default options with the given opts applied.
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,360 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// DefaultPageSize is the page size used when Pagination.Size is not set.
+const DefaultPageSize = 20
+
+// Pagination determines which page of entities is returned by Repository.Paginate.
+type Pagination struct {
+	// Size contains the maximum amount of entities per page. DefaultPageSize is used if it's not greater than zero.
+	Size int
+	// Cursor contains a token returned in Page.Next or Page.Prev. The first page is returned if it's empty.
+	Cursor string
+	// Keyset enables keyset pagination, which pages through entities using the values of the sort fields of the
+	// last entity in the page instead of an offset. Keyset pagination is stable under concurrent inserts, the
+	// primary key is used as tiebreaker if it's not one of the sort fields. Sort fields should not be nullable.
+	Keyset bool
+	// Total enables counting every entity matching the query, which can be expensive on large tables.
+	Total bool
+}
+
+// size returns the page size.
+func (p Pagination) size() int {
+	if p.Size <= 0 {
+		return DefaultPageSize
+	}
+	return p.Size
+}
+
+// Page contains a page of entities returned by Repository.Paginate.
+type Page[E any] struct {
+	// Items contains the entities in the page.
+	Items []E
+	// Total contains the amount of entities matching the query. It's only set if Pagination.Total is enabled.
+	Total *int64
+	// Next contains the cursor used to get the next page. It's empty on the last page.
+	Next string
+	// Prev contains the cursor used to get the previous page. It's empty on the first page.
+	Prev string
+}
+
+// cursor is the decoded representation of a cursor token.
+type cursor struct {
+	// Keyset is true for cursors produced by keyset pagination.
+	Keyset bool `json:"k,omitempty"`
+	// Offset contains the offset of the page for offset pagination.
+	Offset int `json:"o,omitempty"`
+	// Values contains the sort field values of the entity the page starts after for keyset pagination.
+	Values []json.RawMessage `json:"v,omitempty"`
+	// Backward is true if the page ends before the entity instead.
+	Backward bool `json:"b,omitempty"`
+	// Query contains the hash of the sort and filter of the query that produced the cursor.
+	Query string `json:"q,omitempty"`
+}
+
+// encodeCursor encodes the given cursor as an opaque token.
+func encodeCursor(c cursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor decodes the given token. An empty token returns the zero cursor.
+func decodeCursor(token string) (cursor, error) {
+	var c cursor
+	if token == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+	return c, nil
+}
+
+// queryHash returns a hash identifying the sort and filter of the given Query, used to reject cursors produced by a
+// different query. Sort fields are resolved against the given schema, so field and column names are equivalent.
+func queryHash(s *schema.Schema, q Query) (string, error) {
+	sorts := make([]Sort, len(q.Sort))
+	for i, sort := range q.Sort {
+		field, err := lookUpField(s, sort.Field)
+		if err != nil {
+			return "", err
+		}
+		sorts[i] = Sort{Field: field.DBName, Desc: sort.Desc}
+	}
+	b, err := json.Marshal(struct {
+		Sort   []Sort
+		Filter Filter
+	}{Sort: sorts, Filter: q.Filter})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
+
+// fetchFunc returns the entities matching the given Query, skipping offset entities and returning at most limit
+// entities.
+type fetchFunc[E any] func(q Query, offset, limit int) ([]E, error)
+
+// countFunc returns the amount of entities matching the given Filter.
+type countFunc func(f Filter) (int64, error)
+
+// paginate implements Repository.Paginate for any persistence layer able to fetch and count entities.
+func paginate[E any](ctx context.Context, s *schema.Schema, pk *schema.Field, q Query, p Pagination, fetch fetchFunc[E], count countFunc) (Page[E], error) {
+	c, err := decodeCursor(p.Cursor)
+	if err != nil {
+		return Page[E]{}, err
+	}
+	if p.Cursor != "" && c.Keyset != p.Keyset {
+		return Page[E]{}, fmt.Errorf("%w: pagination mode mismatch", ErrInvalidCursor)
+	}
+	hash, err := queryHash(s, q)
+	if err != nil {
+		return Page[E]{}, err
+	}
+	if p.Cursor != "" && c.Query != hash {
+		return Page[E]{}, fmt.Errorf("%w: query mismatch", ErrInvalidCursor)
+	}
+	c.Query = hash
+
+	var page Page[E]
+	if p.Total {
+		total, err := count(q.Filter)
+		if err != nil {
+			return Page[E]{}, err
+		}
+		page.Total = &total
+	}
+
+	if p.Keyset {
+		err = paginateKeyset(ctx, s, pk, q, p.size(), c, fetch, &page)
+	} else {
+		err = paginateOffset(q, p.size(), c, fetch, &page)
+	}
+	if err != nil {
+		return Page[E]{}, err
+	}
+	return page, nil
+}
+
+// paginateOffset fills the given page using offset pagination.
+func paginateOffset[E any](q Query, size int, c cursor, fetch fetchFunc[E], page *Page[E]) error {
+	if c.Offset < 0 {
+		return fmt.Errorf("%w: negative offset", ErrInvalidCursor)
+	}
+	items, err := fetch(q, c.Offset, size+1)
+	if err != nil {
+		return err
+	}
+	if len(items) > size {
+		items = items[:size]
+		if page.Next, err = encodeCursor(cursor{Offset: c.Offset + size, Query: c.Query}); err != nil {
+			return err
+		}
+	}
+	if c.Offset > 0 {
+		prev := c.Offset - size
+		if prev < 0 {
+			prev = 0
+		}
+		if page.Prev, err = encodeCursor(cursor{Offset: prev, Query: c.Query}); err != nil {
+			return err
+		}
+	}
+	page.Items = items
+	return nil
+}
+
+// paginateKeyset fills the given page using keyset pagination.
+func paginateKeyset[E any](ctx context.Context, s *schema.Schema, pk *schema.Field, q Query, size int, c cursor, fetch fetchFunc[E], page *Page[E]) error {
+	sorts, fields, err := keysetSorts(s, pk, q.Sort)
+	if err != nil {
+		return err
+	}
+	q.Sort = sorts
+	if len(q.Fields) > 0 {
+		q = q.Select(keysetMissingFields(q.Fields, s, fields)...)
+	}
+
+	if len(c.Values) > 0 {
+		values, err := decodeKeysetValues(c.Values, fields)
+		if err != nil {
+			return err
+		}
+		q = q.Where(keysetFilter(sorts, values, c.Backward))
+	}
+	if c.Backward {
+		reversed := make([]Sort, len(sorts))
+		for i, s := range sorts {
+			reversed[i] = Sort{Field: s.Field, Desc: !s.Desc}
+		}
+		q.Sort = reversed
+	}
+
+	items, err := fetch(q, 0, size+1)
+	if err != nil {
+		return err
+	}
+	hasMore := len(items) > size
+	if hasMore {
+		items = items[:size]
+	}
+	if c.Backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	page.Items = items
+	if len(items) == 0 {
+		return nil
+	}
+
+	hasNext, hasPrev := hasMore, len(c.Values) > 0
+	if c.Backward {
+		hasNext, hasPrev = true, hasMore
+	}
+	if hasNext {
+		if page.Next, err = keysetCursor(ctx, fields, items[len(items)-1], false, c.Query); err != nil {
+			return err
+		}
+	}
+	if hasPrev {
+		if page.Prev, err = keysetCursor(ctx, fields, items[0], true, c.Query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keysetSorts resolves the given sort fields, appending the primary key as tiebreaker if it's not sorted already.
+func keysetSorts(s *schema.Schema, pk *schema.Field, sorts []Sort) ([]Sort, []*schema.Field, error) {
+	resolved := make([]Sort, 0, len(sorts)+1)
+	fields := make([]*schema.Field, 0, len(sorts)+1)
+	var hasPrimaryKey bool
+	for _, sort := range sorts {
+		field, err := lookUpField(s, sort.Field)
+		if err != nil {
+			return nil, nil, err
+		}
+		hasPrimaryKey = hasPrimaryKey || field == pk
+		resolved = append(resolved, Sort{Field: field.DBName, Desc: sort.Desc})
+		fields = append(fields, field)
+	}
+	if !hasPrimaryKey {
+		resolved = append(resolved, Sort{Field: pk.DBName})
+		fields = append(fields, pk)
+	}
+	return resolved, fields, nil
+}
+
+// keysetMissingFields returns the column names of the given key fields that are not selected already.
+func keysetMissingFields(selected []string, s *schema.Schema, keys []*schema.Field) []string {
+	var missing []string
+	for _, key := range keys {
+		var found bool
+		for _, name := range selected {
+			if s.LookUpField(name) == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, key.DBName)
+		}
+	}
+	return missing
+}
+
+// keysetFilter returns a Filter matching the entities after the given sort field values, or before them if
+// backward is true.
+func keysetFilter(sorts []Sort, values []interface{}, backward bool) Filter {
+	or := make([]Filter, 0, len(sorts))
+	for i, sort := range sorts {
+		and := make([]Filter, 0, i+1)
+		for j := 0; j < i; j++ {
+			and = append(and, Eq(sorts[j].Field, values[j]))
+		}
+		if sort.Desc != backward {
+			and = append(and, Lt(sort.Field, values[i]))
+		} else {
+			and = append(and, Gt(sort.Field, values[i]))
+		}
+		or = append(or, And(and...))
+	}
+	return Or(or...)
+}
+
+// keysetCursor returns the cursor pointing to the given entity, produced by the query identified by hash.
+func keysetCursor(ctx context.Context, fields []*schema.Field, entity interface{}, backward bool, hash string) (string, error) {
+	v := reflect.Indirect(reflect.ValueOf(entity))
+	values := make([]json.RawMessage, len(fields))
+	for i, field := range fields {
+		value, _ := field.ValueOf(ctx, v)
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		values[i] = b
+	}
+	return encodeCursor(cursor{Keyset: true, Values: values, Backward: backward, Query: hash})
+}
+
+// decodeKeysetValues decodes the given cursor values using the types of the given fields.
+func decodeKeysetValues(raw []json.RawMessage, fields []*schema.Field) ([]interface{}, error) {
+	if len(raw) != len(fields) {
+		return nil, fmt.Errorf("%w: sort fields mismatch", ErrInvalidCursor)
+	}
+	values := make([]interface{}, len(raw))
+	for i, field := range fields {
+		v := reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw[i], v.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+		}
+		values[i] = v.Elem().Interface()
+	}
+	return values, nil
+}
+
+// each implements Repository.Each by walking the pages returned by the given paginate function using keyset
+// pagination.
+func each[E any](ctx context.Context, size int, paginate func(p Pagination) (Page[E], error), fn func(entity E) error) error {
+	p := Pagination{Size: size, Keyset: true}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := paginate(p)
+		if err != nil {
+			return err
+		}
+		for _, entity := range page.Items {
+			if err := fn(entity); err != nil {
+				if errors.Is(err, ErrStop) {
+					return nil
+				}
+				return err
+			}
+		}
+		if page.Next == "" {
+			return nil
+		}
+		p.Cursor = page.Next
+	}
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"gorm.io/gorm/schema"
+	"math"
+	"reflect"
+)
+
+// Patch describes a partial update of an entity. Unlike Repository.Update, every field listed in a Patch is
+// updated, including fields set to their zero value. Patches are built using Mask or Fields, and cannot update
+// primary key fields.
+type Patch[E any] struct {
+	entity E
+	fields []string
+	values map[string]interface{}
+}
+
+// Mask returns a Patch updating the given fields with the values of entity. Fields can be either struct field
+// names or column names.
+//
+//	repository.Mask(User{Active: false, Score: 0}, "Active", "Score")
+func Mask[E any](entity E, fields ...string) Patch[E] {
+	return Patch[E]{entity: entity, fields: fields}
+}
+
+// Fields returns a Patch updating the fields used as keys in values, which can be either struct field names or
+// column names. Values must be assignable to the type of their field, numbers are converted between numeric types
+// as long as the conversion doesn't lose data, such as truncating a fraction or changing the sign.
+//
+//	repository.Fields[User](map[string]interface{}{"active": false, "score": 0})
+func Fields[E any](values map[string]interface{}) Patch[E] {
+	return Patch[E]{values: values}
+}
+
+// columns resolves the patch into the values to assign, keyed by column name. It returns ErrInvalidField if a
+// field doesn't exist, it's a primary key field, such as pk, or a value cannot be assigned to its field.
+func (p Patch[E]) columns(ctx context.Context, s *schema.Schema, pk *schema.Field) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(p.fields)+len(p.values))
+	if len(p.fields) > 0 {
+		v := reflect.ValueOf(&p.entity).Elem()
+		for _, name := range p.fields {
+			field, err := patchField(s, pk, name)
+			if err != nil {
+				return nil, err
+			}
+			out[field.DBName], _ = field.ValueOf(ctx, v)
+		}
+	}
+	for name, value := range p.values {
+		field, err := patchField(s, pk, name)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = assignable(field, value); err != nil {
+			return nil, err
+		}
+		out[field.DBName] = value
+	}
+	return out, nil
+}
+
+// mask resolves the patch into its Mask form, setting the values of a Fields patch on an entity. The fields of
+// the returned patch are struct field names. It returns ErrInvalidField if a field doesn't exist, it's a primary
+// key field or a value cannot be assigned to its field.
+func (p Patch[E]) mask(ctx context.Context, s *schema.Schema) (Patch[E], error) {
+	out := Patch[E]{entity: p.entity, fields: make([]string, 0, len(p.fields)+len(p.values))}
+	seen := make(map[string]bool, cap(out.fields))
+	add := func(field *schema.Field) {
+		if !seen[field.Name] {
+			seen[field.Name] = true
+			out.fields = append(out.fields, field.Name)
+		}
+	}
+	for _, name := range p.fields {
+		field, err := patchField(s, nil, name)
+		if err != nil {
+			return Patch[E]{}, err
+		}
+		add(field)
+	}
+
+	v := reflect.ValueOf(&out.entity).Elem()
+	for name, value := range p.values {
+		field, err := patchField(s, nil, name)
+		if err != nil {
+			return Patch[E]{}, err
+		}
+		if value, err = assignable(field, value); err != nil {
+			return Patch[E]{}, err
+		}
+		target := field.ReflectValueOf(ctx, v)
+		if value == nil {
+			target.Set(reflect.Zero(field.FieldType))
+		} else if err := field.Set(ctx, v, value); err != nil {
+			return Patch[E]{}, fmt.Errorf("%w: %s: %v", ErrInvalidField, field.Name, err)
+		}
+		add(field)
+	}
+	return out, nil
+}
+
+// patchField returns the field of the given schema updated by a patch. It returns ErrInvalidField if the field
+// doesn't exist or it's a primary key field, either according to the schema or because it's pk.
+func patchField(s *schema.Schema, pk *schema.Field, name string) (*schema.Field, error) {
+	field, err := lookUpField(s, name)
+	if err != nil {
+		return nil, err
+	}
+	if field.PrimaryKey || field == pk {
+		return nil, fmt.Errorf("%w: %s is a primary key and cannot be patched", ErrInvalidField, field.Name)
+	}
+	return field, nil
+}
+
+// assignable returns value converted to the type of the given field. It returns ErrInvalidField if the value
+// cannot be assigned to the field.
+func assignable(field *schema.Field, value interface{}) (interface{}, error) {
+	if value == nil {
+		switch field.FieldType.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			return nil, nil
+		}
+		if field.FieldType.Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem()) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %s cannot be null", ErrInvalidField, field.Name)
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(field.FieldType), v.Type().AssignableTo(field.IndirectFieldType):
+		return value, nil
+	case isNumber(v.Kind()) && isNumber(field.IndirectFieldType.Kind()):
+		converted, ok := convertNumber(v, field.IndirectFieldType)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s cannot hold %v", ErrInvalidField, field.Name, value)
+		}
+		return converted.Interface(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s expects %s, got %T", ErrInvalidField, field.Name, field.FieldType, value)
+	}
+}
+
+// isNumber returns true if the given kind is a numeric kind.
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// convertNumber converts the number held by v to the numeric type t. It returns false if the conversion loses
+// data, such as truncating a fraction, overflowing t or changing the sign. Floats can be converted into smaller
+// floats as long as they don't overflow, even if they lose precision.
+func convertNumber(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	out := v.Convert(t)
+	if out.CanFloat() && v.CanFloat() {
+		return out, !math.IsInf(out.Float(), 0) || math.IsInf(v.Float(), 0)
+	}
+	return out, out.Convert(v.Type()).Interface() == v.Interface() && negative(out) == negative(v)
+}
+
+// negative reports whether the number held by v is negative.
+func negative(v reflect.Value) bool {
+	switch {
+	case v.CanInt():
+		return v.Int() < 0
+	case v.CanFloat():
+		return v.Float() < 0
+	default:
+		return false
+	}
+}
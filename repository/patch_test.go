@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/schema"
+	"sync"
+	"testing"
+)
+
+func TestAssignable(t *testing.T) {
+	type Score struct {
+		ID     uint
+		Points int8
+		Count  uint32
+		Ratio  float32
+	}
+	s, err := schema.Parse(new(Score), &sync.Map{}, schema.NamingStrategy{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	cases := []struct {
+		name     string
+		field    string
+		value    interface{}
+		expected interface{}
+	}{
+		{name: "int", field: "Points", value: 12, expected: int8(12)},
+		{name: "integral float", field: "Points", value: 12.0, expected: int8(12)},
+		{name: "fraction", field: "Points", value: 12.5},
+		{name: "overflow", field: "Points", value: 300},
+		{name: "unsigned", field: "Count", value: int64(7), expected: uint32(7)},
+		{name: "negative unsigned", field: "Count", value: -1},
+		{name: "float", field: "Ratio", value: 0.1, expected: float32(0.1)},
+		{name: "float overflow", field: "Ratio", value: 1e300},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			value, err := assignable(s.LookUpField(c.field), c.value)
+			if c.expected == nil {
+				assert.ErrorIs(t, err, ErrInvalidField)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, value)
+		})
+	}
+
+	_, err = patchField(s, nil, "ID")
+	assert.ErrorIs(t, err, ErrInvalidField)
+	_, err = patchField(s, s.LookUpField("Count"), "count")
+	assert.ErrorIs(t, err, ErrInvalidField)
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// Operator determines how a Condition compares a field with a value.
+type Operator string
+
+const (
+	// OpEq matches fields equal to the value.
+	OpEq Operator = "eq"
+	// OpNeq matches fields not equal to the value.
+	OpNeq Operator = "neq"
+	// OpGt matches fields greater than the value.
+	OpGt Operator = "gt"
+	// OpGte matches fields greater than or equal to the value.
+	OpGte Operator = "gte"
+	// OpLt matches fields lower than the value.
+	OpLt Operator = "lt"
+	// OpLte matches fields lower than or equal to the value.
+	OpLte Operator = "lte"
+	// OpIn matches fields equal to any of the values. The value must be a []interface{}.
+	OpIn Operator = "in"
+	// OpLike matches fields against an SQL LIKE pattern, where % matches any sequence of characters and _
+	// matches a single character.
+	OpLike Operator = "like"
+	// OpIsNull matches fields that are null.
+	OpIsNull Operator = "is_null"
+	// OpNotNull matches fields that are not null.
+	OpNotNull Operator = "not_null"
+)
+
+// Filter is a condition used to select entities. Filters are built using functions such as Eq, In or Range,
+// and combined using And and Or.
+type Filter interface {
+	filter()
+}
+
+// Condition is a Filter that compares a field with a value.
+type Condition struct {
+	// Field contains either the struct field name or the column name.
+	Field string
+	// Operator determines how the field is compared with the value.
+	Operator Operator
+	// Value contains the value used in the comparison. It's ignored by OpIsNull and OpNotNull.
+	Value interface{}
+}
+
+func (Condition) filter() {}
+
+// Group is a Filter that combines a set of filters. An empty group matches every entity.
+type Group struct {
+	// Or determines whether the group matches entities matching any of the filters, instead of all of them.
+	Or bool
+	// Filters contains the filters combined by the group.
+	Filters []Filter
+}
+
+func (Group) filter() {}
+
+// Eq returns a Filter matching entities whose field is equal to value.
+func Eq(field string, value interface{}) Filter {
+	return Condition{Field: field, Operator: OpEq, Value: value}
+}
+
+// Neq returns a Filter matching entities whose field is not equal to value.
+func Neq(field string, value interface{}) Filter {
+	return Condition{Field: field, Operator: OpNeq, Value: value}
+}
+
+// Gt returns a Filter matching entities whose field is greater than value.
+func Gt(field string, value interface{}) Filter {
+	return Condition{Field: field, Operator: OpGt, Value: value}
+}
+
+// Gte returns a Filter matching entities whose field is greater than or equal to value.
+func Gte(field string, value interface{}) Filter {
+	return Condition{Field: field, Operator: OpGte, Value: value}
+}
+
+// Lt returns a Filter matching entities whose field is lower than value.
+func Lt(field string, value interface{}) Filter {
+	return Condition{Field: field, Operator: OpLt, Value: value}
+}
+
+// Lte returns a Filter matching entities whose field is lower than or equal to value.
+func Lte(field string, value interface{}) Filter {
+	return Condition{Field: field, Operator: OpLte, Value: value}
+}
+
+// In returns a Filter matching entities whose field is equal to any of the given values. A single slice can be
+// provided instead of multiple values.
+//
+//	repository.In("status", "active", "pending")
+//	repository.In("id", []uint{1, 2, 3})
+func In(field string, values ...interface{}) Filter {
+	if len(values) == 1 {
+		v := reflect.ValueOf(values[0])
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+			values = make([]interface{}, v.Len())
+			for i := range values {
+				values[i] = v.Index(i).Interface()
+			}
+		}
+	}
+	return Condition{Field: field, Operator: OpIn, Value: values}
+}
+
+// Like returns a Filter matching entities whose field matches the given SQL LIKE pattern.
+func Like(field string, pattern string) Filter {
+	return Condition{Field: field, Operator: OpLike, Value: pattern}
+}
+
+// IsNull returns a Filter matching entities whose field is null.
+func IsNull(field string) Filter {
+	return Condition{Field: field, Operator: OpIsNull}
+}
+
+// NotNull returns a Filter matching entities whose field is not null.
+func NotNull(field string) Filter {
+	return Condition{Field: field, Operator: OpNotNull}
+}
+
+// Range returns a Filter matching entities whose field is between from and to, both inclusive.
+func Range(field string, from, to interface{}) Filter {
+	return And(Gte(field, from), Lte(field, to))
+}
+
+// And returns a Filter matching entities that match all the given filters.
+func And(filters ...Filter) Filter {
+	return Group{Filters: filters}
+}
+
+// Or returns a Filter matching entities that match any of the given filters.
+func Or(filters ...Filter) Filter {
+	return Group{Or: true, Filters: filters}
+}
+
+// Sort determines the order of the entities returned by a Query.
+type Sort struct {
+	// Field contains either the struct field name or the column name.
+	Field string
+	// Desc determines whether entities are sorted in descending order.
+	Desc bool
+}
+
+// Asc returns a Sort ordering entities by field in ascending order.
+func Asc(field string) Sort {
+	return Sort{Field: field}
+}
+
+// Desc returns a Sort ordering entities by field in descending order.
+func Desc(field string) Sort {
+	return Sort{Field: field, Desc: true}
+}
+
+// Query describes which entities are returned when listing entities from a Repository, in which order and which
+// of their fields are populated. Field names are validated against the entity schema, and can be either struct
+// field names or column names. The zero value matches every entity.
+//
+//	q := repository.NewQuery(
+//		repository.Eq("active", true),
+//		repository.Gt("created_at", since),
+//	).OrderBy(repository.Asc("name")).Select("id", "name")
+type Query struct {
+	// Filter determines which entities are returned. A nil Filter matches every entity.
+	Filter Filter
+	// Sort determines the order of the returned entities, from the most to the least significant field.
+	Sort []Sort
+	// Fields determines which fields are populated. If empty, every field is populated.
+	Fields []string
+}
+
+// NewQuery returns a Query matching entities that match all the given filters.
+func NewQuery(filters ...Filter) Query {
+	return Query{}.Where(filters...)
+}
+
+// Where returns a copy of the query that also requires entities to match all the given filters.
+func (q Query) Where(filters ...Filter) Query {
+	if len(filters) == 0 {
+		return q
+	}
+	all := make([]Filter, 0, len(filters)+1)
+	if q.Filter != nil {
+		all = append(all, q.Filter)
+	}
+	all = append(all, filters...)
+	if len(all) == 1 {
+		q.Filter = all[0]
+	} else {
+		q.Filter = And(all...)
+	}
+	return q
+}
+
+// OrderBy returns a copy of the query that also sorts entities by the given fields.
+func (q Query) OrderBy(sorts ...Sort) Query {
+	q.Sort = append(append(make([]Sort, 0, len(q.Sort)+len(sorts)), q.Sort...), sorts...)
+	return q
+}
+
+// Select returns a copy of the query that also populates the given fields.
+func (q Query) Select(fields ...string) Query {
+	q.Fields = append(append(make([]string, 0, len(q.Fields)+len(fields)), q.Fields...), fields...)
+	return q
+}
+
+// scopeKey is the context key holding the filter restricting the entities of a type visible to SQL and Memory.
+// Scopes are keyed by entity type, so they don't apply to repositories of other entities sharing the context, such
+// as the ones used by hooks or in the same UnitOfWork.
+type scopeKey struct {
+	model reflect.Type
+}
+
+// contextWithScope returns a copy of ctx restricting the operations of SQL and Memory on the entities of the given
+// schema to the ones matching f, along with the scope already held by ctx. It's used by wrappers such as WithTenant
+// to add conditions to every statement, since the context is passed unchanged through the other wrappers.
+func contextWithScope(ctx context.Context, s *schema.Schema, f Filter) context.Context {
+	return context.WithValue(ctx, scopeKey{model: s.ModelType}, scoped(ctx, s, f))
+}
+
+// contextWithoutScope returns a copy of ctx that isn't scoped for the entities of the given schema.
+func contextWithoutScope(ctx context.Context, s *schema.Schema) context.Context {
+	if scopeFromContext(ctx, s) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, scopeKey{model: s.ModelType}, nil)
+}
+
+// scopeFromContext returns the scope held by ctx for the entities of the given schema, or nil if they aren't
+// scoped.
+func scopeFromContext(ctx context.Context, s *schema.Schema) Filter {
+	f, _ := ctx.Value(scopeKey{model: s.ModelType}).(Filter)
+	return f
+}
+
+// scoped returns f restricted to the scope held by ctx for the entities of the given schema.
+func scoped(ctx context.Context, s *schema.Schema, f Filter) Filter {
+	scope := scopeFromContext(ctx, s)
+	switch {
+	case scope == nil:
+		return f
+	case f == nil:
+		return scope
+	default:
+		return And(scope, f)
+	}
+}
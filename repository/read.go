@@ -0,0 +1,103 @@
+package repository
+
+// association describes an association loaded along with the entities.
+type association struct {
+	// path contains the association name, nested associations are separated by dots.
+	path string
+	// filter determines which associated entities are loaded. A nil Filter loads every associated entity.
+	filter Filter
+}
+
+// trashed determines whether a read operation returns soft deleted entities.
+type trashed int
+
+const (
+	// trashedExcluded only returns entities that aren't soft deleted, the default.
+	trashedExcluded trashed = iota
+	// trashedIncluded returns entities regardless of whether they are soft deleted.
+	trashedIncluded
+	// trashedOnly only returns soft deleted entities.
+	trashedOnly
+)
+
+// readOptions contains the configuration of a read operation.
+type readOptions struct {
+	// preloads contains the associations loaded using separate queries.
+	preloads []association
+	// joins contains the associations loaded by joining their tables.
+	joins []association
+	// trashed determines whether soft deleted entities are returned.
+	trashed trashed
+}
+
+// ReadOption configures the read operations of a Repository, such as Get, Find and List.
+type ReadOption func(o *readOptions)
+
+// WithPreload loads the given associations using a separate query per association. Nested associations are
+// separated by dots. Associations are validated against the entity schema, unknown associations return
+// ErrInvalidField.
+//
+//	repository.WithPreload("Orders", "Profile.Address")
+func WithPreload(paths ...string) ReadOption {
+	return func(o *readOptions) {
+		for _, path := range paths {
+			o.preloads = append(o.preloads, association{path: path})
+		}
+	}
+}
+
+// WithPreloadFilter loads the given association like WithPreload, but only the associated entities matching the
+// given Filter. Fields in the filter refer to the associated entity.
+//
+//	repository.WithPreloadFilter("Orders", repository.Eq("status", "paid"))
+func WithPreloadFilter(path string, filter Filter) ReadOption {
+	return func(o *readOptions) {
+		o.preloads = append(o.preloads, association{path: path, filter: filter})
+	}
+}
+
+// WithJoin loads the given associations in the same query, by joining their tables. Only has one and belongs to
+// associations can be joined. Nested associations are separated by dots.
+//
+//	repository.WithJoin("Profile", "Profile.Address")
+func WithJoin(paths ...string) ReadOption {
+	return func(o *readOptions) {
+		for _, path := range paths {
+			o.joins = append(o.joins, association{path: path})
+		}
+	}
+}
+
+// WithJoinFilter loads the given association like WithJoin, but only if it matches the given Filter. The filter
+// is added to the join condition, so entities whose association doesn't match it are still returned, without
+// the association. Fields in the filter refer to the associated entity.
+func WithJoinFilter(path string, filter Filter) ReadOption {
+	return func(o *readOptions) {
+		o.joins = append(o.joins, association{path: path, filter: filter})
+	}
+}
+
+// WithTrashed returns soft deleted entities along with the other entities. Read operations return
+// ErrNotSoftDeletable if the entity has no gorm.DeletedAt field.
+func WithTrashed() ReadOption {
+	return func(o *readOptions) {
+		o.trashed = trashedIncluded
+	}
+}
+
+// OnlyTrashed only returns soft deleted entities. Read operations return ErrNotSoftDeletable if the entity has no
+// gorm.DeletedAt field.
+func OnlyTrashed() ReadOption {
+	return func(o *readOptions) {
+		o.trashed = trashedOnly
+	}
+}
+
+// newReadOptions returns the default read options with the given opts applied.
+func newReadOptions(opts []ReadOption) readOptions {
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
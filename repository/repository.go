@@ -7,22 +7,64 @@ import (
 // Repository contains a set of methods to interact with a persistence layer.
 // The E parameter represents an entity model.
 // The K parameter represent the primary key.
+//
+// Read operations accept ReadOption values, such as WithPreload or WithJoin, to load the associations of the
+// entities. Entities with a gorm.DeletedAt field are soft deleted by Remove, and are only returned by read
+// operations using WithTrashed or OnlyTrashed.
+//
+// Implementations return the errors defined in this package, such as ErrNotFound or ErrConflict, allowing
+// callers to handle them using errors.Is regardless of the persistence layer.
 type Repository[E any, K comparable] interface {
 	// Create creates an entity in a persistence layer.
 	Create(ctx context.Context, entity E) (E, error)
 	// CreateBulk creates a set of entities in a persistence layer.
 	CreateBulk(ctx context.Context, entities []E) ([]E, error)
-	// Get returns an entity from a persistence layer identified by its ID. It returns an error if the entity doesn't exist.
-	Get(ctx context.Context, id K) (E, error)
+	// Upsert creates an entity, or updates it if it conflicts with an existing one. It returns the persisted entity.
+	Upsert(ctx context.Context, entity E, opts ...UpsertOption) (E, error)
+	// UpsertBulk creates a set of entities, updating the ones that conflict with existing entities. It returns the
+	// persisted entities in the same order.
+	UpsertBulk(ctx context.Context, entities []E, opts ...UpsertOption) ([]E, error)
+	// Get returns an entity from a persistence layer identified by its ID. It returns ErrNotFound if the entity doesn't exist.
+	Get(ctx context.Context, id K, opts ...ReadOption) (E, error)
 	// Find returns a set of entities from a persistence layer identified by their ID. It returns
 	// an empty slice if no records were found.
-	Find(ctx context.Context, ids []K) ([]E, error)
-	// Update updates an entity.
+	Find(ctx context.Context, ids []K, opts ...ReadOption) ([]E, error)
+	// FindOrdered returns a set of entities identified by their IDs, in the same order as the given IDs. Repeated
+	// IDs are only returned once, and the result contains the IDs that didn't match any entity.
+	FindOrdered(ctx context.Context, ids []K, opts ...ReadOption) (Result[E, K], error)
+	// List returns the entities matching the given Query. It returns an empty slice if no records were found.
+	List(ctx context.Context, query Query, opts ...ReadOption) ([]E, error)
+	// Paginate returns a page of the entities matching the given Query. It returns ErrInvalidCursor if the
+	// pagination cursor cannot be used with the given query.
+	Paginate(ctx context.Context, query Query, pagination Pagination, opts ...ReadOption) (Page[E], error)
+	// Each calls fn with every entity matching the given Query, loading them in batches using keyset pagination so
+	// memory usage is bounded. The iteration stops when fn returns an error, which is returned unless it's ErrStop,
+	// or when ctx is done.
+	Each(ctx context.Context, query Query, fn func(entity E) error, opts ...ReadOption) error
+	// Update updates the non-zero fields of an entity and returns the persisted entity. It returns ErrNotFound if
+	// the entity doesn't exist.
 	Update(ctx context.Context, id K, entity E) (E, error)
-	// UpdateBulk updates multiple entities with values of entity.
-	UpdateBulk(ctx context.Context, ids []K, entity E) ([]E, error)
+	// Patch updates exactly the fields listed in the given Patch, including zero values, and returns the persisted
+	// entity. It returns ErrNotFound if the entity doesn't exist.
+	Patch(ctx context.Context, id K, patch Patch[E]) (E, error)
+	// UpdateBulk updates multiple entities with values of entity. The result contains the persisted entities and
+	// the IDs that didn't match any entity.
+	UpdateBulk(ctx context.Context, ids []K, entity E) (Result[E, K], error)
 	// Remove removes the given id from the persistence layer.
 	Remove(ctx context.Context, id K) (E, error)
-	// RemoveBulk removes a set of elements from the persistence layer.
-	RemoveBulk(ctx context.Context, ids []K) ([]E, error)
+	// RemoveBulk removes a set of elements from the persistence layer. The result contains the removed entities and
+	// the IDs that didn't match any entity.
+	RemoveBulk(ctx context.Context, ids []K) (Result[E, K], error)
+	// Restore restores a soft deleted entity and returns it. It returns ErrNotFound if the entity doesn't exist,
+	// and ErrNotSoftDeletable if the entity cannot be soft deleted.
+	Restore(ctx context.Context, id K) (E, error)
+	// RestoreBulk restores a set of soft deleted entities. The result contains the restored entities and the IDs
+	// that didn't match any entity. It returns ErrNotSoftDeletable if the entity cannot be soft deleted.
+	RestoreBulk(ctx context.Context, ids []K) (Result[E, K], error)
+	// ForceRemove permanently removes an entity, even if it was soft deleted, and returns it. It returns ErrNotFound
+	// if the entity doesn't exist.
+	ForceRemove(ctx context.Context, id K) (E, error)
+	// ForceRemoveBulk permanently removes a set of entities, even if they were soft deleted. The result contains the
+	// removed entities and the IDs that didn't match any entity.
+	ForceRemoveBulk(ctx context.Context, ids []K) (Result[E, K], error)
 }
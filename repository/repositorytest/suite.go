@@ -0,0 +1,468 @@
+// Package repositorytest contains a conformance suite for implementations of repository.Repository. Running the
+// suite proves that an implementation behaves like the ones provided by the repository package.
+//
+//	func TestRepository(t *testing.T) {
+//		repositorytest.RunSuite(t, func(t *testing.T) repository.Repository[repositorytest.Entity, uint] {
+//			return NewMyRepository[repositorytest.Entity, uint](t)
+//		})
+//	}
+package repositorytest
+
+import (
+	"context"
+	"github.com/gojaguar/jaguar/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// Entity is the entity stored by the repositories under test. It has an integer primary key generated by the
+// persistence layer, timestamps, soft deletes and a unique index on Email.
+type Entity struct {
+	gorm.Model
+	Name   string
+	Email  string `gorm:"uniqueIndex"`
+	Score  int
+	Active bool
+}
+
+// Factory returns an empty repository storing Entity values. It's called once per test, so tests don't share
+// state.
+type Factory func(t *testing.T) repository.Repository[Entity, uint]
+
+// RunSuite runs the conformance suite against the repositories returned by factory. Every test runs as a subtest
+// of t, using a new repository.
+func RunSuite(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, r repository.Repository[Entity, uint])
+	}{
+		{"Create", testCreate},
+		{"Create_Conflict", testCreateConflict},
+		{"CreateBulk", testCreateBulk},
+		{"CreateBulk_Atomic", testCreateBulkAtomic},
+		{"Upsert", testUpsert},
+		{"Get_NotFound", testGetNotFound},
+		{"Find", testFind},
+		{"FindOrdered", testFindOrdered},
+		{"List", testList},
+		{"Paginate", testPaginate},
+		{"Each", testEach},
+		{"Update", testUpdate},
+		{"Update_NotFound", testUpdateNotFound},
+		{"Patch", testPatch},
+		{"UpdateBulk", testUpdateBulk},
+		{"Remove", testRemove},
+		{"RemoveBulk", testRemoveBulk},
+		{"Restore", testRestore},
+		{"ForceRemove", testForceRemove},
+		{"ContextCanceled", testContextCanceled},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, factory(t))
+		})
+	}
+}
+
+// seed creates the entities used by most tests, returning them in creation order.
+func seed(t *testing.T, r repository.Repository[Entity, uint]) []Entity {
+	entities, err := r.CreateBulk(context.Background(), []Entity{
+		{Name: "Marcos", Email: "marcos@example.com", Score: 10, Active: true},
+		{Name: "Andres", Email: "andres@example.com", Score: 20, Active: true},
+		{Name: "Andrew", Email: "andrew@example.com", Score: 30},
+	})
+	require.NoError(t, err)
+	require.Len(t, entities, 3)
+	return entities
+}
+
+// ids returns the primary keys of the given entities.
+func ids(entities []Entity) []uint {
+	out := make([]uint, len(entities))
+	for i, e := range entities {
+		out[i] = e.ID
+	}
+	return out
+}
+
+// names returns the names of the given entities.
+func names(entities []Entity) []string {
+	out := make([]string, len(entities))
+	for i, e := range entities {
+		out[i] = e.Name
+	}
+	return out
+}
+
+func testCreate(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	created, err := r.Create(ctx, Entity{Name: "Marcos", Email: "marcos@example.com"})
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	stored, err := r.Get(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, stored.ID)
+	assert.Equal(t, "Marcos", stored.Name)
+	assert.Equal(t, "marcos@example.com", stored.Email)
+}
+
+func testCreateConflict(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	entities := seed(t, r)
+
+	_, err := r.Create(ctx, Entity{Model: gorm.Model{ID: entities[0].ID}, Email: "other@example.com"})
+	assert.ErrorIs(t, err, repository.ErrConflict)
+
+	_, err = r.Create(ctx, Entity{Email: entities[1].Email})
+	assert.ErrorIs(t, err, repository.ErrConflict)
+}
+
+func testCreateBulk(t *testing.T, r repository.Repository[Entity, uint]) {
+	entities := seed(t, r)
+	assert.Equal(t, []string{"Marcos", "Andres", "Andrew"}, names(entities))
+	for _, e := range entities {
+		assert.NotZero(t, e.ID)
+	}
+
+	list, err := r.Find(context.Background(), ids(entities))
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+}
+
+func testCreateBulkAtomic(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	seed(t, r)
+
+	_, err := r.CreateBulk(ctx, []Entity{
+		{Name: "John", Email: "john@example.com"},
+		{Name: "Conflict", Email: "marcos@example.com"},
+	})
+	assert.ErrorIs(t, err, repository.ErrConflict)
+
+	list, err := r.List(ctx, repository.NewQuery(repository.Eq("email", "john@example.com")))
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func testUpsert(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	entities := seed(t, r)
+
+	upserted, err := r.Upsert(ctx, Entity{Model: gorm.Model{ID: entities[0].ID}, Name: "Mark", Email: entities[0].Email})
+	require.NoError(t, err)
+	assert.Equal(t, entities[0].ID, upserted.ID)
+	assert.Equal(t, "Mark", upserted.Name)
+	assert.Zero(t, upserted.Score)
+
+	bulk, err := r.UpsertBulk(ctx, []Entity{
+		{Name: "John", Email: "john@example.com"},
+		{Name: "Andy", Email: entities[1].Email, Score: 50},
+	}, repository.OnConflict("email"), repository.DoUpdate("name"))
+	require.NoError(t, err)
+	require.Len(t, bulk, 2)
+	assert.NotZero(t, bulk[0].ID)
+	assert.Equal(t, "John", bulk[0].Name)
+	assert.Equal(t, entities[1].ID, bulk[1].ID)
+	assert.Equal(t, "Andy", bulk[1].Name)
+	assert.Equal(t, 20, bulk[1].Score)
+
+	nothing, err := r.Upsert(ctx, Entity{Name: "Unknown", Email: entities[2].Email}, repository.OnConflict("email"), repository.DoNothing())
+	require.NoError(t, err)
+	assert.Equal(t, "Andrew", nothing.Name)
+}
+
+func testGetNotFound(t *testing.T, r repository.Repository[Entity, uint]) {
+	result, err := r.Get(context.Background(), 1000)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.Zero(t, result)
+}
+
+func testFind(t *testing.T, r repository.Repository[Entity, uint]) {
+	entities := seed(t, r)
+	list, err := r.Find(context.Background(), []uint{entities[2].ID, 1000, entities[0].ID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{entities[0].ID, entities[2].ID}, ids(list))
+
+	list, err = r.Find(context.Background(), []uint{1000})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func testFindOrdered(t *testing.T, r repository.Repository[Entity, uint]) {
+	entities := seed(t, r)
+	result, err := r.FindOrdered(context.Background(), []uint{entities[2].ID, 1000, entities[0].ID, entities[2].ID, 1000})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{entities[2].ID, entities[0].ID}, ids(result.Items))
+	assert.Equal(t, []uint{1000}, result.Missing)
+}
+
+func testList(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	seed(t, r)
+
+	list, err := r.List(ctx, repository.NewQuery(repository.Eq("active", true)).OrderBy(repository.Desc("score")))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Andres", "Marcos"}, names(list))
+
+	list, err = r.List(ctx, repository.NewQuery(repository.Or(
+		repository.Like("name", "And%"),
+		repository.In("score", 10),
+	), repository.Range("score", 10, 25)).OrderBy(repository.Asc("Name")))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Andres", "Marcos"}, names(list))
+
+	list, err = r.List(ctx, repository.Query{}.Select("name").OrderBy(repository.Asc("score")))
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, "Marcos", list[0].Name)
+	assert.Zero(t, list[0].Score)
+
+	list, err = r.List(ctx, repository.NewQuery(repository.Eq("name", "Unknown")))
+	require.NoError(t, err)
+	assert.NotNil(t, list)
+	assert.Empty(t, list)
+
+	_, err = r.List(ctx, repository.NewQuery(repository.Eq("unknown", 1)))
+	assert.ErrorIs(t, err, repository.ErrInvalidField)
+}
+
+func testPaginate(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	seed(t, r)
+	q := repository.Query{}.OrderBy(repository.Asc("name"))
+
+	for _, keyset := range []bool{false, true} {
+		page, err := r.Paginate(ctx, q, repository.Pagination{Size: 2, Keyset: keyset, Total: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Andres", "Andrew"}, names(page.Items))
+		require.NotNil(t, page.Total)
+		assert.Equal(t, int64(3), *page.Total)
+		assert.Empty(t, page.Prev)
+
+		page, err = r.Paginate(ctx, q, repository.Pagination{Size: 2, Keyset: keyset, Cursor: page.Next})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Marcos"}, names(page.Items))
+		assert.Empty(t, page.Next)
+
+		page, err = r.Paginate(ctx, q, repository.Pagination{Size: 2, Keyset: keyset, Cursor: page.Prev})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Andres", "Andrew"}, names(page.Items))
+	}
+
+	_, err := r.Paginate(ctx, q, repository.Pagination{Cursor: "invalid"})
+	assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+
+	page, err := r.Paginate(ctx, q, repository.Pagination{Size: 1, Keyset: true})
+	require.NoError(t, err)
+	_, err = r.Paginate(ctx, repository.Query{}.OrderBy(repository.Asc("email")), repository.Pagination{Size: 1, Keyset: true, Cursor: page.Next})
+	assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+}
+
+func testEach(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	seed(t, r)
+
+	var visited []string
+	err := r.Each(ctx, repository.Query{}.OrderBy(repository.Desc("score")), func(entity Entity) error {
+		visited = append(visited, entity.Name)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Andrew", "Andres", "Marcos"}, visited)
+
+	visited = nil
+	err = r.Each(ctx, repository.Query{}.OrderBy(repository.Desc("score")), func(entity Entity) error {
+		visited = append(visited, entity.Name)
+		return repository.ErrStop
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Andrew"}, visited)
+}
+
+func testUpdate(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	entities := seed(t, r)
+
+	updated, err := r.Update(ctx, entities[0].ID, Entity{Name: "Mark"})
+	require.NoError(t, err)
+	assert.Equal(t, entities[0].ID, updated.ID)
+	assert.Equal(t, "Mark", updated.Name)
+	assert.Equal(t, entities[0].Email, updated.Email)
+	assert.False(t, updated.CreatedAt.IsZero())
+
+	_, err = r.Update(ctx, entities[0].ID, Entity{Email: entities[1].Email})
+	assert.ErrorIs(t, err, repository.ErrConflict)
+}
+
+func testUpdateNotFound(t *testing.T, r repository.Repository[Entity, uint]) {
+	_, err := r.Update(context.Background(), 1000, Entity{Name: "Mark"})
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	_, err = r.Patch(context.Background(), 1000, repository.Mask(Entity{}, "Name"))
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testPatch(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	entities := seed(t, r)
+
+	patched, err := r.Patch(ctx, entities[0].ID, repository.Mask(Entity{}, "Active", "Score"))
+	require.NoError(t, err)
+	assert.False(t, patched.Active)
+	assert.Zero(t, patched.Score)
+	assert.Equal(t, "Marcos", patched.Name)
+
+	patched, err = r.Patch(ctx, entities[1].ID, repository.Fields[Entity](map[string]interface{}{"name": "", "score": 0}))
+	require.NoError(t, err)
+	assert.Empty(t, patched.Name)
+	assert.Zero(t, patched.Score)
+
+	_, err = r.Patch(ctx, entities[1].ID, repository.Fields[Entity](map[string]interface{}{"unknown": 1}))
+	assert.ErrorIs(t, err, repository.ErrInvalidField)
+}
+
+func testUpdateBulk(t *testing.T, r repository.Repository[Entity, uint]) {
+	entities := seed(t, r)
+	result, err := r.UpdateBulk(context.Background(), []uint{entities[0].ID, 1000, entities[2].ID}, Entity{Score: 99})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{entities[0].ID, entities[2].ID}, ids(result.Items))
+	for _, e := range result.Items {
+		assert.Equal(t, 99, e.Score)
+	}
+	assert.Equal(t, []uint{1000}, result.Missing)
+}
+
+func testRemove(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	entities := seed(t, r)
+
+	removed, err := r.Remove(ctx, entities[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, entities[0].ID, removed.ID)
+	assert.Equal(t, "Marcos", removed.Name)
+
+	_, err = r.Get(ctx, entities[0].ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	_, err = r.Remove(ctx, entities[0].ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	list, err := r.List(ctx, repository.Query{})
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func testRemoveBulk(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	entities := seed(t, r)
+
+	result, err := r.RemoveBulk(ctx, []uint{entities[0].ID, 1000, entities[1].ID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{entities[0].ID, entities[1].ID}, ids(result.Items))
+	assert.Equal(t, []uint{1000}, result.Missing)
+
+	list, err := r.List(ctx, repository.Query{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{entities[2].ID}, ids(list))
+}
+
+func testRestore(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	entities := seed(t, r)
+
+	_, err := r.RemoveBulk(ctx, []uint{entities[0].ID, entities[1].ID})
+	require.NoError(t, err)
+
+	trashed, err := r.Get(ctx, entities[0].ID, repository.WithTrashed())
+	require.NoError(t, err)
+	assert.True(t, trashed.DeletedAt.Valid)
+
+	list, err := r.List(ctx, repository.Query{}, repository.OnlyTrashed())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{entities[0].ID, entities[1].ID}, ids(list))
+
+	page, err := r.Paginate(ctx, repository.Query{}, repository.Pagination{Total: true}, repository.WithTrashed())
+	require.NoError(t, err)
+	require.NotNil(t, page.Total)
+	assert.Equal(t, int64(3), *page.Total)
+
+	restored, err := r.Restore(ctx, entities[0].ID)
+	require.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+	assert.Equal(t, "Marcos", restored.Name)
+
+	got, err := r.Get(ctx, entities[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Marcos", got.Name)
+
+	_, err = r.Restore(ctx, 1000)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	result, err := r.RestoreBulk(ctx, []uint{entities[1].ID, 1000})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{entities[1].ID}, ids(result.Items))
+	assert.Equal(t, []uint{1000}, result.Missing)
+
+	list, err = r.List(ctx, repository.Query{})
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+}
+
+func testForceRemove(t *testing.T, r repository.Repository[Entity, uint]) {
+	ctx := context.Background()
+	entities := seed(t, r)
+
+	_, err := r.Remove(ctx, entities[0].ID)
+	require.NoError(t, err)
+
+	removed, err := r.ForceRemove(ctx, entities[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Marcos", removed.Name)
+
+	_, err = r.Get(ctx, entities[0].ID, repository.WithTrashed())
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	result, err := r.ForceRemoveBulk(ctx, []uint{entities[1].ID, 1000})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{entities[1].ID}, ids(result.Items))
+	assert.Equal(t, []uint{1000}, result.Missing)
+
+	list, err := r.List(ctx, repository.Query{}, repository.WithTrashed())
+	require.NoError(t, err)
+	assert.Equal(t, []uint{entities[2].ID}, ids(list))
+}
+
+func testContextCanceled(t *testing.T, r repository.Repository[Entity, uint]) {
+	entities := seed(t, r)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := r.Create(ctx, Entity{Email: "john@example.com"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = r.Get(ctx, entities[0].ID)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = r.List(ctx, repository.Query{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = r.Update(ctx, entities[0].ID, Entity{Name: "Mark"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = r.Remove(ctx, entities[0].ID)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = r.Each(ctx, repository.Query{}, func(entity Entity) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	stored, err := r.Get(context.Background(), entities[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Marcos", stored.Name)
+}
@@ -0,0 +1,50 @@
+package repositorytest
+
+import (
+	"github.com/gojaguar/jaguar/repository"
+	"github.com/gojaguar/jaguar/telemetry"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	RunSuite(t, func(t *testing.T) repository.Repository[Entity, uint] {
+		return repository.NewMemory[Entity, uint]()
+	})
+}
+
+func TestSQL(t *testing.T) {
+	RunSuite(t, func(t *testing.T) repository.Repository[Entity, uint] {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "suite.db")), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		})
+		require.NoError(t, err)
+		require.NoError(t, db.AutoMigrate(&Entity{}))
+		t.Cleanup(func() {
+			sqlDB, err := db.DB()
+			require.NoError(t, err)
+			require.NoError(t, sqlDB.Close())
+		})
+		return repository.NewRepositorySQL[Entity, uint](db)
+	})
+}
+
+func TestCache(t *testing.T) {
+	RunSuite(t, func(t *testing.T) repository.Repository[Entity, uint] {
+		return repository.WithCache[Entity, uint](repository.NewMemory[Entity, uint](), repository.NewLRU[uint, repository.Cached[Entity]](100), repository.CacheOptions{
+			TTL:         time.Minute,
+			NegativeTTL: time.Minute,
+		})
+	})
+}
+
+func TestInstrument(t *testing.T) {
+	RunSuite(t, func(t *testing.T) repository.Repository[Entity, uint] {
+		return repository.Instrument(repository.NewMemory[Entity, uint](), "entities", repository.WithRegistry(telemetry.NewRegistry()))
+	})
+}
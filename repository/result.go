@@ -0,0 +1,9 @@
+package repository
+
+// Result contains the outcome of an operation on a set of entities identified by their IDs.
+type Result[E any, K comparable] struct {
+	// Items contains the entities affected by the operation.
+	Items []E
+	// Missing contains the IDs that didn't match any entity, in the order they were given.
+	Missing []K
+}
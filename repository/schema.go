@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+)
+
+// primaryKey returns the primary key field of the given schema. If name isn't empty, the field identified by name
+// is returned instead.
+func primaryKey(s *schema.Schema, name string) (*schema.Field, error) {
+	if name != "" {
+		return lookUpField(s, name)
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMissingPrimaryKey, s.Name)
+	}
+	return s.PrioritizedPrimaryField, nil
+}
+
+// softDeleteField returns the gorm.DeletedAt field of the given schema, used to soft delete entities. It returns
+// nil if the entity cannot be soft deleted.
+func softDeleteField(s *schema.Schema) *schema.Field {
+	deletedAt := reflect.TypeOf(gorm.DeletedAt{})
+	for _, field := range s.Fields {
+		if field.DBName != "" && field.FieldType == deletedAt {
+			return field
+		}
+	}
+	return nil
+}
+
+// checkTrashed returns ErrNotSoftDeletable if soft deleted entities are requested by the given read options but
+// the entity of the given schema, whose soft delete field is deletedAt, cannot be soft deleted.
+func checkTrashed(s *schema.Schema, deletedAt *schema.Field, o readOptions) error {
+	if o.trashed != trashedExcluded && deletedAt == nil {
+		return fmt.Errorf("%w: %s", ErrNotSoftDeletable, s.Name)
+	}
+	return nil
+}
+
+// lookUpField returns the field of the entity schema identified by name, which can be either a struct field name
+// or a column name. It returns ErrInvalidField if the field doesn't exist or isn't stored in a column.
+func lookUpField(s *schema.Schema, name string) (*schema.Field, error) {
+	field := s.LookUpField(name)
+	if field == nil || field.DBName == "" {
+		return nil, fmt.Errorf("%w: %s.%s", ErrInvalidField, s.Name, name)
+	}
+	return field, nil
+}
+
+// lookUpRelationship returns the relationships of the given schema along path, where nested relationships are
+// separated by dots. It returns ErrInvalidField if any of the relationships doesn't exist.
+func lookUpRelationship(s *schema.Schema, path string) ([]*schema.Relationship, error) {
+	names := strings.Split(path, ".")
+	rels := make([]*schema.Relationship, len(names))
+	current := s
+	for i, name := range names {
+		rel, ok := current.Relationships.Relations[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s has no association %s", ErrInvalidField, s.Name, path)
+		}
+		rels[i] = rel
+		current = rel.FieldSchema
+	}
+	return rels, nil
+}
+
+// primaryKeyValue returns the value of the given primary key field of an entity, converted to K.
+func primaryKeyValue[K comparable](ctx context.Context, pk *schema.Field, v reflect.Value) K {
+	value, _ := pk.ValueOf(ctx, v)
+	if id, ok := value.(K); ok {
+		return id
+	}
+	var id K
+	if v := reflect.ValueOf(value); v.IsValid() && v.CanConvert(reflect.TypeOf(id)) {
+		id = v.Convert(reflect.TypeOf(id)).Interface().(K)
+	}
+	return id
+}
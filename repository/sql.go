@@ -2,71 +2,387 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sync"
 )
 
 // SQL implements Repository using gorm.
 type SQL[E any, K comparable] struct {
-	db *gorm.DB
+	db      *gorm.DB
+	options options
+
+	once      sync.Once
+	schema    *schema.Schema
+	pk        *schema.Field
+	deletedAt *schema.Field
+	err       error
+}
+
+// parse parses the schema of the entity and resolves its primary key and soft delete field. The schema is only
+// parsed once.
+func (r *SQL[E, K]) parse() error {
+	r.once.Do(func() {
+		stmt := &gorm.Statement{DB: r.db}
+		if err := stmt.Parse(new(E)); err != nil {
+			r.err = err
+			return
+		}
+		r.schema = stmt.Schema
+		r.deletedAt = softDeleteField(stmt.Schema)
+		r.pk, r.err = primaryKey(stmt.Schema, r.options.primaryKey)
+	})
+	return r.err
+}
+
+// model returns a statement on the entity table bound to ctx, restricted to the entities in the scope of ctx.
+func (r *SQL[E, K]) model(ctx context.Context) (*gorm.DB, error) {
+	db := r.db.WithContext(ctx).Model(new(E))
+	expr, err := buildFilter(r.schema, scopeFromContext(ctx, r.schema))
+	if err != nil {
+		return nil, err
+	}
+	if expr != nil {
+		db = db.Where(expr)
+	}
+	return db, nil
+}
+
+// primaryKeyColumn returns the primary key column. Columns are quoted by gorm according to the database dialect.
+func (r *SQL[E, K]) primaryKeyColumn() clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: r.pk.DBName}
+}
+
+// primaryKeyEquals returns a condition matching the entity identified by id.
+func (r *SQL[E, K]) primaryKeyEquals(id K) clause.Expression {
+	return clause.Eq{Column: r.primaryKeyColumn(), Value: id}
+}
+
+// primaryKeyIn returns a condition matching the entities identified by ids.
+func (r *SQL[E, K]) primaryKeyIn(ids []K) clause.Expression {
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+	return clause.IN{Column: r.primaryKeyColumn(), Values: values}
+}
+
+// primaryKeyOf returns the primary key of the given entity.
+func (r *SQL[E, K]) primaryKeyOf(ctx context.Context, entity *E) K {
+	return primaryKeyValue[K](ctx, r.pk, reflect.ValueOf(entity).Elem())
+}
+
+// missing returns the given IDs that don't identify any of the given entities, without duplicates.
+func (r *SQL[E, K]) missing(ctx context.Context, ids []K, entities []E) []K {
+	found := make(map[K]bool, len(entities))
+	for i := range entities {
+		found[r.primaryKeyOf(ctx, &entities[i])] = true
+	}
+	var missing []K
+	for _, id := range ids {
+		if !found[id] {
+			found[id] = true
+			missing = append(missing, id)
+		}
+	}
+	return missing
 }
 
 // Create creates an entity in a persistence layer.
 func (r *SQL[E, K]) Create(ctx context.Context, entity E) (E, error) {
 	if err := r.db.WithContext(ctx).Model(new(E)).Create(&entity).Error; err != nil {
 		var zero E
-		return zero, err
+		return zero, translateError(err)
 	}
 	return entity, nil
 }
 
-// CreateBulk creates a set of entities in a persistence layer.
+// CreateBulk creates a set of entities in a persistence layer. Entities are created atomically in batches, unless
+// the repository was configured with WithBestEffort.
 func (r *SQL[E, K]) CreateBulk(ctx context.Context, entities []E) ([]E, error) {
-	if err := r.db.WithContext(ctx).Model(new(E)).Create(&entities).Error; err != nil {
-		return nil, err
+	if r.options.bestEffort {
+		return r.createEach(ctx, entities)
+	}
+	if len(entities) == 0 {
+		return []E{}, nil
+	}
+	err := r.transaction(ctx, func(tx *SQL[E, K]) error {
+		return tx.db.Model(new(E)).CreateInBatches(&entities, r.options.batch()).Error
+	})
+	if err != nil {
+		return nil, translateError(err)
 	}
 	return entities, nil
 }
 
-// Get returns an entity from a persistence layer identified by its ID. It returns an error if the entity doesn't exist.
-func (r *SQL[E, K]) Get(ctx context.Context, id K) (E, error) {
-	var out E
-	if err := r.db.WithContext(ctx).Model(new(E)).Where("id = ?", id).First(&out).Error; err != nil {
+// Upsert creates an entity, or updates it if it conflicts with an existing one. It returns the persisted entity.
+func (r *SQL[E, K]) Upsert(ctx context.Context, entity E, opts ...UpsertOption) (E, error) {
+	out, err := r.UpsertBulk(ctx, []E{entity}, opts...)
+	if err != nil {
 		var zero E
 		return zero, err
 	}
+	return out[0], nil
+}
+
+// UpsertBulk creates a set of entities, updating the ones that conflict with existing entities. It returns the
+// persisted entities in the same order. Entities are written in batches within a single transaction.
+func (r *SQL[E, K]) UpsertBulk(ctx context.Context, entities []E, opts ...UpsertOption) ([]E, error) {
+	if err := r.parse(); err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		return []E{}, nil
+	}
+	onConflict, fields, err := r.onConflict(newUpsertOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]E, 0, len(entities))
+	err = r.transaction(ctx, func(tx *SQL[E, K]) error {
+		for _, chunk := range chunks(entities, r.options.batch()) {
+			if err := tx.db.Model(new(E)).Clauses(onConflict).Create(&chunk).Error; err != nil {
+				return translateError(err)
+			}
+			persisted, err := tx.reload(ctx, chunk, fields)
+			if err != nil {
+				return err
+			}
+			out = append(out, persisted...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Get returns an entity from a persistence layer identified by its ID. It returns ErrNotFound if the entity doesn't exist.
+func (r *SQL[E, K]) Get(ctx context.Context, id K, opts ...ReadOption) (E, error) {
+	var out E
+	if err := r.parse(); err != nil {
+		return out, err
+	}
+	db, err := r.model(ctx)
+	if err != nil {
+		return out, err
+	}
+	if db, err = r.applyRead(db, opts); err != nil {
+		return out, err
+	}
+	if err := db.Where(r.primaryKeyEquals(id)).First(&out).Error; err != nil {
+		var zero E
+		return zero, translateError(err)
+	}
 	return out, nil
 }
 
 // Find returns a set of entities from a persistence layer identified by their IDs. It returns
 // an empty slice if no records were found.
-func (r *SQL[E, K]) Find(ctx context.Context, ids []K) ([]E, error) {
+func (r *SQL[E, K]) Find(ctx context.Context, ids []K, opts ...ReadOption) ([]E, error) {
+	if err := r.parse(); err != nil {
+		return nil, err
+	}
+	db, err := r.model(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if db, err = r.applyRead(db, opts); err != nil {
+		return nil, err
+	}
 	var out []E
-	if err := r.db.WithContext(ctx).Model(new(E)).Where("id IN (?)", ids).Find(&out).Error; err != nil {
+	if err := db.Where(r.primaryKeyIn(ids)).Find(&out).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return out, nil
+}
+
+// FindOrdered returns a set of entities identified by their IDs, in the same order as the given IDs. Repeated
+// IDs are only returned once, and the result contains the IDs that didn't match any entity.
+func (r *SQL[E, K]) FindOrdered(ctx context.Context, ids []K, opts ...ReadOption) (Result[E, K], error) {
+	if err := r.parse(); err != nil {
+		return Result[E, K]{}, err
+	}
+	ids = unique(ids)
+
+	found := make(map[K]E, len(ids))
+	for _, chunk := range chunks(ids, r.options.batch()) {
+		items, err := r.Find(ctx, chunk, opts...)
+		if err != nil {
+			return Result[E, K]{}, err
+		}
+		for i := range items {
+			found[r.primaryKeyOf(ctx, &items[i])] = items[i]
+		}
+	}
+
+	result := Result[E, K]{Items: make([]E, 0, len(found))}
+	for _, id := range ids {
+		if entity, ok := found[id]; ok {
+			result.Items = append(result.Items, entity)
+		} else {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+	return result, nil
+}
+
+// List returns the entities matching the given Query. It returns an empty slice if no records were found.
+func (r *SQL[E, K]) List(ctx context.Context, query Query, opts ...ReadOption) ([]E, error) {
+	if err := r.parse(); err != nil {
+		return nil, err
+	}
+	db, err := r.model(ctx)
+	if err != nil {
 		return nil, err
 	}
+	if db, err = r.applyRead(db, opts); err != nil {
+		return nil, err
+	}
+	if db, err = r.applyQuery(db, query); err != nil {
+		return nil, err
+	}
+	out := make([]E, 0)
+	if err := db.Find(&out).Error; err != nil {
+		return nil, translateError(err)
+	}
 	return out, nil
 }
 
-// Update updates an entity.
+// Paginate returns a page of the entities matching the given Query. It returns ErrInvalidCursor if the
+// pagination cursor cannot be used with the given query.
+func (r *SQL[E, K]) Paginate(ctx context.Context, query Query, pagination Pagination, opts ...ReadOption) (Page[E], error) {
+	if err := r.parse(); err != nil {
+		return Page[E]{}, err
+	}
+	fetch := func(q Query, offset, limit int) ([]E, error) {
+		db, err := r.model(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if db, err = r.applyRead(db, opts); err != nil {
+			return nil, err
+		}
+		if db, err = r.applyQuery(db, q); err != nil {
+			return nil, err
+		}
+		out := make([]E, 0, limit)
+		if err := db.Offset(offset).Limit(limit).Find(&out).Error; err != nil {
+			return nil, translateError(err)
+		}
+		return out, nil
+	}
+	count := func(f Filter) (int64, error) {
+		db, err := r.model(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if db, err = r.applyTrashed(db, newReadOptions(opts)); err != nil {
+			return 0, err
+		}
+		if db, err = r.applyQuery(db, Query{Filter: f}); err != nil {
+			return 0, err
+		}
+		var total int64
+		if err := db.Count(&total).Error; err != nil {
+			return 0, translateError(err)
+		}
+		return total, nil
+	}
+	return paginate(ctx, r.schema, r.pk, query, pagination, fetch, count)
+}
+
+// Each calls fn with every entity matching the given Query, loading them in batches using keyset pagination so
+// memory usage is bounded. The iteration stops when fn returns an error, which is returned unless it's ErrStop,
+// or when ctx is done.
+func (r *SQL[E, K]) Each(ctx context.Context, query Query, fn func(entity E) error, opts ...ReadOption) error {
+	return each(ctx, r.options.batch(), func(p Pagination) (Page[E], error) {
+		return r.Paginate(ctx, query, p, opts...)
+	}, fn)
+}
+
+// Update updates the non-zero fields of an entity and returns the persisted entity. It returns ErrNotFound if
+// the entity doesn't exist.
 func (r *SQL[E, K]) Update(ctx context.Context, id K, entity E) (E, error) {
-	if err := r.db.WithContext(ctx).Model(new(E)).Where("id = ?", id).Updates(&entity).Error; err != nil {
+	if err := r.parse(); err != nil {
 		var zero E
 		return zero, err
 	}
-	return entity, nil
+	db, err := r.model(ctx)
+	if err != nil {
+		var zero E
+		return zero, err
+	}
+	if err := db.Where(r.primaryKeyEquals(id)).Updates(&entity).Error; err != nil {
+		var zero E
+		return zero, translateError(err)
+	}
+	return r.Get(ctx, id)
 }
 
-// UpdateBulk updates multiple entities with values of entity.
-func (r *SQL[E, K]) UpdateBulk(ctx context.Context, ids []K, entity E) ([]E, error) {
-	if err := r.db.WithContext(ctx).Model(new(E)).Where("id IN (?)", ids).Updates(&entity).Error; err != nil {
-		return nil, err
+// Patch updates exactly the fields listed in the given Patch, including zero values, and returns the persisted
+// entity. It returns ErrNotFound if the entity doesn't exist.
+func (r *SQL[E, K]) Patch(ctx context.Context, id K, patch Patch[E]) (E, error) {
+	if err := r.parse(); err != nil {
+		var zero E
+		return zero, err
+	}
+	values, err := patch.columns(ctx, r.schema, r.pk)
+	if err != nil {
+		var zero E
+		return zero, err
+	}
+	if len(values) > 0 {
+		db, err := r.model(ctx)
+		if err != nil {
+			var zero E
+			return zero, err
+		}
+		if err := db.Where(r.primaryKeyEquals(id)).Updates(values).Error; err != nil {
+			var zero E
+			return zero, translateError(err)
+		}
 	}
+	return r.Get(ctx, id)
+}
 
-	var result []E
-	if err := r.db.WithContext(ctx).Model(new(E)).Where("id IN (?)", ids).Find(&result).Error; err != nil {
-		return nil, err
+// UpdateBulk updates multiple entities with values of entity. The result contains the persisted entities and
+// the IDs that didn't match any entity. Entities are updated atomically in batches, unless the repository was
+// configured with WithBestEffort.
+func (r *SQL[E, K]) UpdateBulk(ctx context.Context, ids []K, entity E) (Result[E, K], error) {
+	if err := r.parse(); err != nil {
+		return Result[E, K]{}, err
+	}
+	if r.options.bestEffort {
+		return r.updateEach(ctx, ids, entity)
 	}
 
+	var result Result[E, K]
+	err := r.transaction(ctx, func(tx *SQL[E, K]) error {
+		for _, chunk := range chunks(ids, r.options.batch()) {
+			db, err := tx.model(ctx)
+			if err != nil {
+				return err
+			}
+			if err := db.Where(r.primaryKeyIn(chunk)).Updates(&entity).Error; err != nil {
+				return translateError(err)
+			}
+			items, err := tx.Find(ctx, chunk)
+			if err != nil {
+				return err
+			}
+			result.Items = append(result.Items, items...)
+		}
+		return nil
+	})
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	result.Missing = r.missing(ctx, ids, result.Items)
 	return result, nil
 }
 
@@ -78,31 +394,158 @@ func (r *SQL[E, K]) Remove(ctx context.Context, id K) (E, error) {
 		return zero, err
 	}
 
-	if err := r.db.WithContext(ctx).Model(new(E)).Where("id = ?", id).Delete(&entity).Error; err != nil {
+	db, err := r.model(ctx)
+	if err != nil {
 		var zero E
 		return zero, err
 	}
+	if err := db.Where(r.primaryKeyEquals(id)).Delete(&entity).Error; err != nil {
+		var zero E
+		return zero, translateError(err)
+	}
 
 	return entity, nil
 }
 
-// RemoveBulk removes a set of elements from the persistence layer.
-func (r *SQL[E, K]) RemoveBulk(ctx context.Context, ids []K) ([]E, error) {
-	result, err := r.Find(ctx, ids)
+// RemoveBulk removes a set of elements from the persistence layer. The result contains the removed entities and
+// the IDs that didn't match any entity. Entities are removed atomically in batches, unless the repository was
+// configured with WithBestEffort.
+func (r *SQL[E, K]) RemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	if err := r.parse(); err != nil {
+		return Result[E, K]{}, err
+	}
+	if r.options.bestEffort {
+		return r.removeEach(ctx, ids)
+	}
+
+	var result Result[E, K]
+	err := r.transaction(ctx, func(tx *SQL[E, K]) error {
+		for _, chunk := range chunks(ids, r.options.batch()) {
+			items, err := tx.lockIn(ctx, chunk)
+			if err != nil {
+				return err
+			}
+			if len(items) == 0 {
+				continue
+			}
+			db, err := tx.model(ctx)
+			if err != nil {
+				return err
+			}
+			if err := db.Where(r.primaryKeyIn(chunk)).Delete(&items).Error; err != nil {
+				return translateError(err)
+			}
+			result.Items = append(result.Items, items...)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return Result[E, K]{}, err
 	}
+	result.Missing = r.missing(ctx, ids, result.Items)
+	return result, nil
+}
 
-	if err := r.db.WithContext(ctx).Model(new(E)).Where("id IN (?)", ids).Delete(&result).Error; err != nil {
+// unscoped returns a repository bound to the same database that ignores soft deletes, reading soft deleted
+// entities and removing entities permanently.
+func (r *SQL[E, K]) unscoped() (*SQL[E, K], error) {
+	repository := &SQL[E, K]{db: r.db.Unscoped(), options: r.options}
+	if err := repository.parse(); err != nil {
 		return nil, err
 	}
+	return repository, nil
+}
+
+// restoreIn restores the soft deleted entities identified by ids.
+func (r *SQL[E, K]) restoreIn(ctx context.Context, ids []K) error {
+	db, err := r.model(ctx)
+	if err != nil {
+		return err
+	}
+	return translateError(db.Unscoped().Where(r.primaryKeyIn(ids)).Update(r.deletedAt.DBName, nil).Error)
+}
+
+// Restore restores a soft deleted entity and returns it. It returns ErrNotFound if the entity doesn't exist, and
+// ErrNotSoftDeletable if the entity cannot be soft deleted.
+func (r *SQL[E, K]) Restore(ctx context.Context, id K) (E, error) {
+	var zero E
+	if err := r.parse(); err != nil {
+		return zero, err
+	}
+	if r.deletedAt == nil {
+		return zero, fmt.Errorf("%w: %s", ErrNotSoftDeletable, r.schema.Name)
+	}
+	if err := r.restoreIn(ctx, []K{id}); err != nil {
+		return zero, err
+	}
+	return r.Get(ctx, id)
+}
+
+// RestoreBulk restores a set of soft deleted entities. The result contains the restored entities and the IDs that
+// didn't match any entity. Entities are restored atomically in batches.
+func (r *SQL[E, K]) RestoreBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	if err := r.parse(); err != nil {
+		return Result[E, K]{}, err
+	}
+	if r.deletedAt == nil {
+		return Result[E, K]{}, fmt.Errorf("%w: %s", ErrNotSoftDeletable, r.schema.Name)
+	}
 
+	var result Result[E, K]
+	err := r.transaction(ctx, func(tx *SQL[E, K]) error {
+		for _, chunk := range chunks(ids, r.options.batch()) {
+			if err := tx.restoreIn(ctx, chunk); err != nil {
+				return err
+			}
+			items, err := tx.Find(ctx, chunk)
+			if err != nil {
+				return err
+			}
+			result.Items = append(result.Items, items...)
+		}
+		return nil
+	})
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	result.Missing = r.missing(ctx, ids, result.Items)
 	return result, nil
 }
 
+// ForceRemove permanently removes an entity, even if it was soft deleted, and returns it. It returns ErrNotFound if
+// the entity doesn't exist.
+func (r *SQL[E, K]) ForceRemove(ctx context.Context, id K) (E, error) {
+	if err := r.parse(); err != nil {
+		var zero E
+		return zero, err
+	}
+	repository, err := r.unscoped()
+	if err != nil {
+		var zero E
+		return zero, err
+	}
+	return repository.Remove(ctx, id)
+}
+
+// ForceRemoveBulk permanently removes a set of entities, even if they were soft deleted. The result contains the
+// removed entities and the IDs that didn't match any entity. Entities are removed atomically in batches, unless
+// the repository was configured with WithBestEffort.
+func (r *SQL[E, K]) ForceRemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	if err := r.parse(); err != nil {
+		return Result[E, K]{}, err
+	}
+	repository, err := r.unscoped()
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	return repository.RemoveBulk(ctx, ids)
+}
+
 // NewRepositorySQL initializes a new implementation of Repository using an SQL ORM: gorm.
-func NewRepositorySQL[E any, K comparable](db *gorm.DB) Repository[E, K] {
+// The primary key is read from the entity schema, unless it's set with the WithPrimaryKey option.
+func NewRepositorySQL[E any, K comparable](db *gorm.DB, opts ...Option) Repository[E, K] {
 	return &SQL[E, K]{
-		db: db,
+		db:      db,
+		options: newOptions(opts),
 	}
 }
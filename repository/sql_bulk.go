@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// transaction runs fn in a transaction, using a repository bound to it. A savepoint is used if the repository
+// already runs in a transaction.
+func (r *SQL[E, K]) transaction(ctx context.Context, fn func(tx *SQL[E, K]) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repository := &SQL[E, K]{db: tx, options: r.options}
+		if err := repository.parse(); err != nil {
+			return err
+		}
+		return fn(repository)
+	})
+}
+
+// chunks splits s in chunks of the given size.
+func chunks[T any](s []T, size int) [][]T {
+	out := make([][]T, 0, (len(s)+size-1)/size)
+	for len(s) > size {
+		out = append(out, s[:size])
+		s = s[size:]
+	}
+	if len(s) > 0 {
+		out = append(out, s)
+	}
+	return out
+}
+
+// unique returns the given IDs without duplicates, keeping the order of their first occurrence.
+func unique[K comparable](ids []K) []K {
+	seen := make(map[K]bool, len(ids))
+	out := make([]K, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// bulkError returns a BulkError containing the given errors, or nil if there are none.
+func bulkError(errs []ItemError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &BulkError{Errors: errs}
+}
+
+// createEach creates every entity independently, returning the created entities and the errors of the ones that
+// failed.
+func (r *SQL[E, K]) createEach(ctx context.Context, entities []E) ([]E, error) {
+	out := make([]E, 0, len(entities))
+	var errs []ItemError
+	for i, entity := range entities {
+		err := r.transaction(ctx, func(tx *SQL[E, K]) error {
+			var err error
+			entity, err = tx.Create(ctx, entity)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, ItemError{Index: i, Err: err})
+			continue
+		}
+		out = append(out, entity)
+	}
+	return out, bulkError(errs)
+}
+
+// updateEach updates every entity independently, returning the persisted entities and the errors of the ones
+// that failed.
+func (r *SQL[E, K]) updateEach(ctx context.Context, ids []K, entity E) (Result[E, K], error) {
+	var result Result[E, K]
+	var errs []ItemError
+	for i, id := range ids {
+		var updated E
+		err := r.transaction(ctx, func(tx *SQL[E, K]) error {
+			var err error
+			updated, err = tx.Update(ctx, id, entity)
+			return err
+		})
+		switch {
+		case errors.Is(err, ErrNotFound):
+			result.Missing = append(result.Missing, id)
+		case err != nil:
+			errs = append(errs, ItemError{Index: i, Err: err})
+		default:
+			result.Items = append(result.Items, updated)
+		}
+	}
+	return result, bulkError(errs)
+}
+
+// removeEach removes every entity independently, returning the removed entities and the errors of the ones that
+// failed.
+func (r *SQL[E, K]) removeEach(ctx context.Context, ids []K) (Result[E, K], error) {
+	var result Result[E, K]
+	var errs []ItemError
+	for i, id := range ids {
+		var removed E
+		err := r.transaction(ctx, func(tx *SQL[E, K]) error {
+			var err error
+			removed, err = tx.Remove(ctx, id)
+			return err
+		})
+		switch {
+		case errors.Is(err, ErrNotFound):
+			result.Missing = append(result.Missing, id)
+		case err != nil:
+			errs = append(errs, ItemError{Index: i, Err: err})
+		default:
+			result.Items = append(result.Items, removed)
+		}
+	}
+	return result, bulkError(errs)
+}
+
+// lockIn returns the entities identified by ids, locking them until the end of the transaction on databases
+// supporting row-level locks.
+func (r *SQL[E, K]) lockIn(ctx context.Context, ids []K) ([]E, error) {
+	db, err := r.model(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []E
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where(r.primaryKeyIn(ids)).Find(&out).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return out, nil
+}
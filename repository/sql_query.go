@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// lookUpColumn returns the column of the given schema identified by the given field name.
+func lookUpColumn(s *schema.Schema, name string) (clause.Column, error) {
+	field, err := lookUpField(s, name)
+	if err != nil {
+		return clause.Column{}, err
+	}
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}, nil
+}
+
+// buildFilter translates the given Filter into a gorm clause expression, resolving fields against the given schema.
+// It returns nil if the filter matches every entity.
+func buildFilter(s *schema.Schema, f Filter) (clause.Expression, error) {
+	switch f := f.(type) {
+	case nil:
+		return nil, nil
+	case Condition:
+		return buildCondition(s, f)
+	case Group:
+		exprs := make([]clause.Expression, 0, len(f.Filters))
+		for _, child := range f.Filters {
+			expr, err := buildFilter(s, child)
+			if err != nil {
+				return nil, err
+			}
+			if expr != nil {
+				exprs = append(exprs, expr)
+			}
+		}
+		switch {
+		case len(exprs) == 0:
+			return nil, nil
+		case f.Or:
+			return clause.Or(exprs...), nil
+		default:
+			return clause.And(exprs...), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported filter type %T", f)
+	}
+}
+
+// buildCondition translates the given Condition into a gorm clause expression, resolving its field against the
+// given schema.
+func buildCondition(s *schema.Schema, c Condition) (clause.Expression, error) {
+	column, err := lookUpColumn(s, c.Field)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Operator {
+	case OpEq:
+		return clause.Eq{Column: column, Value: c.Value}, nil
+	case OpNeq:
+		return clause.Neq{Column: column, Value: c.Value}, nil
+	case OpGt:
+		return clause.Gt{Column: column, Value: c.Value}, nil
+	case OpGte:
+		return clause.Gte{Column: column, Value: c.Value}, nil
+	case OpLt:
+		return clause.Lt{Column: column, Value: c.Value}, nil
+	case OpLte:
+		return clause.Lte{Column: column, Value: c.Value}, nil
+	case OpIn:
+		values, ok := c.Value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operator %s expects a []interface{} value, got %T", c.Operator, c.Value)
+		}
+		return clause.IN{Column: column, Values: values}, nil
+	case OpLike:
+		return clause.Like{Column: column, Value: c.Value}, nil
+	case OpIsNull:
+		return clause.Eq{Column: column, Value: nil}, nil
+	case OpNotNull:
+		return clause.Neq{Column: column, Value: nil}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", c.Operator)
+	}
+}
+
+// applyQuery adds the conditions, order and selected fields of the given Query to db.
+func (r *SQL[E, K]) applyQuery(db *gorm.DB, q Query) (*gorm.DB, error) {
+	expr, err := buildFilter(r.schema, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if expr != nil {
+		db = db.Where(expr)
+	}
+
+	if len(q.Sort) > 0 {
+		orderBy := clause.OrderBy{Columns: make([]clause.OrderByColumn, len(q.Sort))}
+		for i, s := range q.Sort {
+			column, err := lookUpColumn(r.schema, s.Field)
+			if err != nil {
+				return nil, err
+			}
+			orderBy.Columns[i] = clause.OrderByColumn{Column: column, Desc: s.Desc}
+		}
+		db = db.Clauses(orderBy)
+	}
+
+	if len(q.Fields) > 0 {
+		columns := make([]string, len(q.Fields))
+		for i, name := range q.Fields {
+			field, err := lookUpField(r.schema, name)
+			if err != nil {
+				return nil, err
+			}
+			columns[i] = field.DBName
+		}
+		db = db.Select(columns)
+	}
+	return db, nil
+}
+
+// applyTrashed makes db read the soft deleted entities requested by the given read options.
+func (r *SQL[E, K]) applyTrashed(db *gorm.DB, o readOptions) (*gorm.DB, error) {
+	if err := checkTrashed(r.schema, r.deletedAt, o); err != nil {
+		return nil, err
+	}
+	switch o.trashed {
+	case trashedIncluded:
+		return db.Unscoped(), nil
+	case trashedOnly:
+		return db.Unscoped().Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: r.deletedAt.DBName}, Value: nil}), nil
+	default:
+		return db, nil
+	}
+}
+
+// applyRead adds the associations loaded by the given read options to db. Associations are validated against the
+// entity schema.
+func (r *SQL[E, K]) applyRead(db *gorm.DB, opts []ReadOption) (*gorm.DB, error) {
+	o := newReadOptions(opts)
+	db, err := r.applyTrashed(db, o)
+	if err != nil {
+		return nil, err
+	}
+	for _, preload := range o.preloads {
+		rels, err := lookUpRelationship(r.schema, preload.path)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := buildFilter(rels[len(rels)-1].FieldSchema, preload.filter)
+		if err != nil {
+			return nil, err
+		}
+		if expr == nil {
+			db = db.Preload(preload.path)
+			continue
+		}
+		db = db.Preload(preload.path, func(tx *gorm.DB) *gorm.DB {
+			return tx.Where(expr)
+		})
+	}
+	for _, join := range o.joins {
+		rels, err := lookUpRelationship(r.schema, join.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range rels {
+			if rel.Type != schema.HasOne && rel.Type != schema.BelongsTo {
+				return nil, fmt.Errorf("%w: %s cannot be joined, only has one and belongs to associations can", ErrInvalidField, join.path)
+			}
+		}
+		expr, err := buildFilter(rels[len(rels)-1].FieldSchema, join.filter)
+		if err != nil {
+			return nil, err
+		}
+		if expr == nil {
+			db = db.Joins(join.path)
+			continue
+		}
+		db = db.Joins(join.path, r.db.Session(&gorm.Session{NewDB: true}).Where(expr))
+	}
+	return db, nil
+}
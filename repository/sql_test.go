@@ -15,6 +15,42 @@ type Test struct {
 	LastName  string
 }
 
+type Account struct {
+	UUID string `gorm:"primaryKey"`
+	Name string
+}
+
+type Profile struct {
+	UserID uint `gorm:"uniqueIndex"`
+	Bio    string
+}
+
+type Member struct {
+	ID    uint
+	Email string `gorm:"uniqueIndex"`
+	Name  string
+}
+
+type Author struct {
+	ID      uint
+	Name    string
+	Profile AuthorProfile
+	Books   []Book
+}
+
+type AuthorProfile struct {
+	ID       uint
+	AuthorID uint
+	Bio      string
+}
+
+type Book struct {
+	ID        uint
+	AuthorID  uint
+	Title     string
+	Published bool
+}
+
 func TestSQL(t *testing.T) {
 	suite.Run(t, new(SQLTestSuite))
 }
@@ -42,7 +78,7 @@ func (s *SQLTestSuite) SetupTest() {
 	s.repository = &SQL[Test, uint]{
 		db: s.tx,
 	}
-	s.Require().NoError(s.tx.Migrator().AutoMigrate(&Test{}))
+	s.Require().NoError(s.tx.Migrator().AutoMigrate(&Test{}, &Account{}, &Profile{}, &Member{}, &Author{}, &AuthorProfile{}, &Book{}))
 }
 
 func (s *SQLTestSuite) TearDownTest() {
@@ -91,7 +127,14 @@ func (s *SQLTestSuite) TestGet() {
 func (s *SQLTestSuite) TestGet_NotFound() {
 	s.createMockData()
 	result, err := s.repository.Get(context.Background(), 5)
-	s.Assert().Error(err)
+	s.Assert().ErrorIs(err, ErrNotFound)
+	s.Assert().Zero(result)
+}
+
+func (s *SQLTestSuite) TestCreate_Conflict() {
+	s.createMockData()
+	result, err := s.repository.Create(context.Background(), Test{Model: gorm.Model{ID: 1}})
+	s.Assert().ErrorIs(err, ErrConflict)
 	s.Assert().Zero(result)
 }
 
@@ -100,6 +143,9 @@ func (s *SQLTestSuite) TestUpdate() {
 	result, err := s.repository.Update(context.Background(), 1, Test{FirstName: "Changed"})
 	s.Assert().NoError(err)
 	s.Assert().NotZero(result)
+	s.Assert().Equal(uint(1), result.ID)
+	s.Assert().Equal("Huck", result.LastName)
+	s.Assert().False(result.CreatedAt.IsZero())
 
 	result, err = s.repository.Get(context.Background(), 1)
 	s.Assert().NoError(err)
@@ -129,6 +175,84 @@ func (s *SQLTestSuite) TestRemove() {
 	s.Assert().Zero(result)
 }
 
+func (s *SQLTestSuite) TestRestore() {
+	ctx := context.Background()
+	s.createMockData()
+	_, err := s.repository.RemoveBulk(ctx, []uint{1, 2})
+	s.Require().NoError(err)
+
+	trashed, err := s.repository.List(ctx, Query{}, OnlyTrashed())
+	s.Require().NoError(err)
+	s.Assert().Len(trashed, 2)
+	all, err := s.repository.Find(ctx, []uint{1, 2, 3}, WithTrashed())
+	s.Require().NoError(err)
+	s.Assert().Len(all, 3)
+	page, err := s.repository.Paginate(ctx, Query{}, Pagination{Total: true}, OnlyTrashed())
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(2), *page.Total)
+
+	restored, err := s.repository.Restore(ctx, 1)
+	s.Require().NoError(err)
+	s.Assert().False(restored.DeletedAt.Valid)
+	s.Assert().Equal("Marcos", restored.FirstName)
+
+	result, err := s.repository.RestoreBulk(ctx, []uint{2, 3, 4})
+	s.Require().NoError(err)
+	s.Assert().Len(result.Items, 2)
+	s.Assert().Equal([]uint{4}, result.Missing)
+
+	_, err = s.repository.Restore(ctx, 4)
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	list, err := s.repository.List(ctx, Query{})
+	s.Require().NoError(err)
+	s.Assert().Len(list, 3)
+}
+
+func (s *SQLTestSuite) TestForceRemove() {
+	ctx := context.Background()
+	s.createMockData()
+	_, err := s.repository.Remove(ctx, 1)
+	s.Require().NoError(err)
+
+	removed, err := s.repository.ForceRemove(ctx, 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("Marcos", removed.FirstName)
+	_, err = s.repository.Get(ctx, 1, WithTrashed())
+	s.Assert().ErrorIs(err, ErrNotFound)
+	_, err = s.repository.ForceRemove(ctx, 1)
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	result, err := s.repository.ForceRemoveBulk(ctx, []uint{2, 3, 4})
+	s.Require().NoError(err)
+	s.Assert().Len(result.Items, 2)
+	s.Assert().Equal([]uint{4}, result.Missing)
+
+	var count int64
+	s.Require().NoError(s.tx.Unscoped().Model(&Test{}).Count(&count).Error)
+	s.Assert().Zero(count)
+}
+
+func (s *SQLTestSuite) TestNotSoftDeletable() {
+	ctx := context.Background()
+	members := NewRepositorySQL[Member, uint](s.tx)
+	created, err := members.Create(ctx, Member{Email: "marcos@example.com"})
+	s.Require().NoError(err)
+
+	_, err = members.Restore(ctx, created.ID)
+	s.Assert().ErrorIs(err, ErrNotSoftDeletable)
+	_, err = members.RestoreBulk(ctx, []uint{created.ID})
+	s.Assert().ErrorIs(err, ErrNotSoftDeletable)
+	_, err = members.List(ctx, Query{}, WithTrashed())
+	s.Assert().ErrorIs(err, ErrNotSoftDeletable)
+	_, err = members.Paginate(ctx, Query{}, Pagination{}, OnlyTrashed())
+	s.Assert().ErrorIs(err, ErrNotSoftDeletable)
+
+	removed, err := members.ForceRemove(ctx, created.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(created.ID, removed.ID)
+}
+
 func (s *SQLTestSuite) TestCreateBulk() {
 	result, err := s.repository.Get(context.Background(), 1)
 	s.Assert().Error(err)
@@ -156,3 +280,583 @@ func (s *SQLTestSuite) TestCreateBulk() {
 	s.Assert().NoError(err)
 	s.Assert().Len(res, 3)
 }
+
+func (s *SQLTestSuite) TestPrimaryKey_Schema() {
+	repository := NewRepositorySQL[Account, string](s.tx)
+	_, err := repository.CreateBulk(context.Background(), []Account{
+		{UUID: "a", Name: "Marcos"},
+		{UUID: "b", Name: "Andres"},
+	})
+	s.Require().NoError(err)
+
+	result, err := repository.Get(context.Background(), "b")
+	s.Assert().NoError(err)
+	s.Assert().Equal("Andres", result.Name)
+
+	_, err = repository.Update(context.Background(), "a", Account{Name: "Changed"})
+	s.Assert().NoError(err)
+
+	list, err := repository.Find(context.Background(), []string{"a", "b"})
+	s.Assert().NoError(err)
+	s.Assert().Len(list, 2)
+
+	removed, err := repository.Remove(context.Background(), "a")
+	s.Assert().NoError(err)
+	s.Assert().Equal("Changed", removed.Name)
+}
+
+func (s *SQLTestSuite) TestPrimaryKey_Option() {
+	repository := NewRepositorySQL[Profile, uint](s.tx, WithPrimaryKey("UserID"))
+	_, err := repository.CreateBulk(context.Background(), []Profile{
+		{UserID: 10, Bio: "Gopher"},
+		{UserID: 20, Bio: "Rustacean"},
+	})
+	s.Require().NoError(err)
+
+	result, err := repository.Get(context.Background(), 20)
+	s.Assert().NoError(err)
+	s.Assert().Equal("Rustacean", result.Bio)
+
+	removed, err := repository.RemoveBulk(context.Background(), []uint{10, 20})
+	s.Assert().NoError(err)
+	s.Assert().Len(removed.Items, 2)
+	s.Assert().Empty(removed.Missing)
+}
+
+func (s *SQLTestSuite) TestPrimaryKey_Invalid() {
+	repository := NewRepositorySQL[Profile, uint](s.tx)
+	_, err := repository.Get(context.Background(), 10)
+	s.Assert().ErrorIs(err, ErrMissingPrimaryKey)
+
+	repository = NewRepositorySQL[Profile, uint](s.tx, WithPrimaryKey("unknown"))
+	_, err = repository.Get(context.Background(), 10)
+	s.Assert().ErrorIs(err, ErrInvalidField)
+}
+
+func (s *SQLTestSuite) TestList() {
+	s.createMockData()
+	list, err := s.repository.List(context.Background(), NewQuery(Eq("last_name", "Huck")).OrderBy(Asc("FirstName")))
+	s.Assert().NoError(err)
+	s.Require().Len(list, 2)
+	s.Assert().Equal("Andres", list[0].FirstName)
+	s.Assert().Equal("Marcos", list[1].FirstName)
+
+	list, err = s.repository.List(context.Background(), NewQuery(Or(
+		Like("first_name", "And%"),
+		In("id", []uint{1}),
+	)).OrderBy(Desc("id")))
+	s.Assert().NoError(err)
+	s.Require().Len(list, 3)
+	s.Assert().Equal("Andrew", list[0].FirstName)
+	s.Assert().Equal("Marcos", list[2].FirstName)
+
+	list, err = s.repository.List(context.Background(), NewQuery(Range("id", 2, 3), Neq("first_name", "Andrew")))
+	s.Assert().NoError(err)
+	s.Require().Len(list, 1)
+	s.Assert().Equal("Andres", list[0].FirstName)
+
+	list, err = s.repository.List(context.Background(), NewQuery(IsNull("deleted_at"), NotNull("created_at")))
+	s.Assert().NoError(err)
+	s.Assert().Len(list, 3)
+
+	list, err = s.repository.List(context.Background(), NewQuery(Eq("last_name", "Unknown")))
+	s.Assert().NoError(err)
+	s.Assert().NotNil(list)
+	s.Assert().Empty(list)
+}
+
+func (s *SQLTestSuite) TestList_Select() {
+	s.createMockData()
+	list, err := s.repository.List(context.Background(), Query{}.Select("ID", "first_name").OrderBy(Asc("id")))
+	s.Assert().NoError(err)
+	s.Require().Len(list, 3)
+	s.Assert().Equal(uint(1), list[0].ID)
+	s.Assert().Equal("Marcos", list[0].FirstName)
+	s.Assert().Empty(list[0].LastName)
+}
+
+func (s *SQLTestSuite) TestList_InvalidField() {
+	_, err := s.repository.List(context.Background(), NewQuery(Eq("unknown", 1)))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+
+	_, err = s.repository.List(context.Background(), Query{}.OrderBy(Asc("unknown")))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+
+	_, err = s.repository.List(context.Background(), Query{}.Select("unknown"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+}
+
+func (s *SQLTestSuite) TestPaginate_Offset() {
+	s.createMockData()
+	ctx := context.Background()
+	q := Query{}.OrderBy(Asc("first_name"))
+
+	page, err := s.repository.Paginate(ctx, q, Pagination{Size: 2, Total: true})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 2)
+	s.Assert().Equal("Andres", page.Items[0].FirstName)
+	s.Assert().Equal("Andrew", page.Items[1].FirstName)
+	s.Require().NotNil(page.Total)
+	s.Assert().Equal(int64(3), *page.Total)
+	s.Assert().Empty(page.Prev)
+	s.Require().NotEmpty(page.Next)
+
+	page, err = s.repository.Paginate(ctx, q, Pagination{Size: 2, Cursor: page.Next})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 1)
+	s.Assert().Equal("Marcos", page.Items[0].FirstName)
+	s.Assert().Nil(page.Total)
+	s.Assert().Empty(page.Next)
+	s.Require().NotEmpty(page.Prev)
+
+	page, err = s.repository.Paginate(ctx, q, Pagination{Size: 2, Cursor: page.Prev})
+	s.Require().NoError(err)
+	s.Assert().Len(page.Items, 2)
+	s.Assert().Empty(page.Prev)
+}
+
+func (s *SQLTestSuite) TestPaginate_Keyset() {
+	s.createMockData()
+	ctx := context.Background()
+	q := NewQuery(Neq("first_name", "Unknown")).OrderBy(Asc("last_name"))
+
+	page, err := s.repository.Paginate(ctx, q, Pagination{Size: 2, Keyset: true})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 2)
+	s.Assert().Equal("Andrew", page.Items[0].FirstName)
+	s.Assert().Equal("Marcos", page.Items[1].FirstName)
+	s.Assert().Empty(page.Prev)
+	s.Require().NotEmpty(page.Next)
+
+	// Entities inserted before the cursor don't shift the following pages.
+	s.Require().NoError(s.tx.Create(&Test{FirstName: "Aaron", LastName: "Adams"}).Error)
+
+	page, err = s.repository.Paginate(ctx, q, Pagination{Size: 2, Keyset: true, Cursor: page.Next})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 1)
+	s.Assert().Equal("Andres", page.Items[0].FirstName)
+	s.Assert().Empty(page.Next)
+	s.Require().NotEmpty(page.Prev)
+
+	page, err = s.repository.Paginate(ctx, q, Pagination{Size: 2, Keyset: true, Cursor: page.Prev})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 2)
+	s.Assert().Equal("Andrew", page.Items[0].FirstName)
+	s.Assert().Equal("Marcos", page.Items[1].FirstName)
+	s.Assert().NotEmpty(page.Next)
+	s.Assert().NotEmpty(page.Prev)
+}
+
+func (s *SQLTestSuite) TestPaginate_Keyset_Select() {
+	s.createMockData()
+	page, err := s.repository.Paginate(context.Background(), Query{}.Select("first_name").OrderBy(Desc("first_name")), Pagination{Size: 1, Keyset: true})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 1)
+	s.Assert().Equal("Marcos", page.Items[0].FirstName)
+	s.Assert().Equal(uint(1), page.Items[0].ID)
+
+	page, err = s.repository.Paginate(context.Background(), Query{}.Select("first_name").OrderBy(Desc("first_name")), Pagination{Size: 1, Keyset: true, Cursor: page.Next})
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 1)
+	s.Assert().Equal("Andrew", page.Items[0].FirstName)
+}
+
+func (s *SQLTestSuite) TestPaginate_InvalidCursor() {
+	s.createMockData()
+	ctx := context.Background()
+
+	_, err := s.repository.Paginate(ctx, Query{}, Pagination{Cursor: "not a cursor"})
+	s.Assert().ErrorIs(err, ErrInvalidCursor)
+
+	page, err := s.repository.Paginate(ctx, Query{}, Pagination{Size: 1})
+	s.Require().NoError(err)
+	_, err = s.repository.Paginate(ctx, Query{}, Pagination{Size: 1, Keyset: true, Cursor: page.Next})
+	s.Assert().ErrorIs(err, ErrInvalidCursor)
+
+	page, err = s.repository.Paginate(ctx, Query{}, Pagination{Size: 1, Keyset: true})
+	s.Require().NoError(err)
+	_, err = s.repository.Paginate(ctx, Query{}.OrderBy(Asc("last_name")), Pagination{Size: 1, Keyset: true, Cursor: page.Next})
+	s.Assert().ErrorIs(err, ErrInvalidCursor)
+
+	q := Query{}.OrderBy(Asc("first_name"))
+	page, err = s.repository.Paginate(ctx, q, Pagination{Size: 1, Keyset: true})
+	s.Require().NoError(err)
+	_, err = s.repository.Paginate(ctx, Query{}.OrderBy(Asc("last_name")), Pagination{Size: 1, Keyset: true, Cursor: page.Next})
+	s.Assert().ErrorIs(err, ErrInvalidCursor)
+	_, err = s.repository.Paginate(ctx, q.Where(Eq("last_name", "Huck")), Pagination{Size: 1, Keyset: true, Cursor: page.Next})
+	s.Assert().ErrorIs(err, ErrInvalidCursor)
+	_, err = s.repository.Paginate(ctx, Query{}.OrderBy(Asc("FirstName")), Pagination{Size: 1, Keyset: true, Cursor: page.Next})
+	s.Assert().NoError(err)
+
+	page, err = s.repository.Paginate(ctx, q, Pagination{Size: 1})
+	s.Require().NoError(err)
+	_, err = s.repository.Paginate(ctx, q.Where(Eq("last_name", "Huck")), Pagination{Size: 1, Cursor: page.Next})
+	s.Assert().ErrorIs(err, ErrInvalidCursor)
+}
+
+func (s *SQLTestSuite) TestUpsert() {
+	s.createMockData()
+	ctx := context.Background()
+
+	out, err := s.repository.Upsert(ctx, Test{Model: gorm.Model{ID: 1}, FirstName: "Mark", LastName: "Huck"})
+	s.Require().NoError(err)
+	s.Assert().Equal(uint(1), out.ID)
+	s.Assert().Equal("Mark", out.FirstName)
+	s.Assert().False(out.CreatedAt.IsZero())
+
+	out, err = s.repository.Upsert(ctx, Test{FirstName: "John", LastName: "Doe"})
+	s.Require().NoError(err)
+	s.Assert().Equal(uint(4), out.ID)
+
+	out, err = s.repository.Upsert(ctx, Test{Model: gorm.Model{ID: 2}, FirstName: "Andy", LastName: "Unknown"}, DoUpdate("first_name"))
+	s.Require().NoError(err)
+	s.Assert().Equal("Andy", out.FirstName)
+	s.Assert().Equal("Huck", out.LastName)
+
+	out, err = s.repository.Upsert(ctx, Test{Model: gorm.Model{ID: 3}, FirstName: "Unknown"}, DoNothing())
+	s.Require().NoError(err)
+	s.Assert().Equal("Andrew", out.FirstName)
+
+	_, err = s.repository.Upsert(ctx, Test{}, OnConflict("unknown"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+}
+
+func (s *SQLTestSuite) TestUpsertBulk() {
+	ctx := context.Background()
+	members := NewRepositorySQL[Member, uint](s.tx)
+
+	_, err := members.Create(ctx, Member{Email: "marcos@example.com", Name: "Marcos"})
+	s.Require().NoError(err)
+
+	out, err := members.UpsertBulk(ctx, []Member{
+		{Email: "andres@example.com", Name: "Andres"},
+		{Email: "marcos@example.com", Name: "Marcos Huck"},
+	}, OnConflict("Email"), DoUpdate("Name"))
+	s.Require().NoError(err)
+	s.Require().Len(out, 2)
+	s.Assert().Equal("andres@example.com", out[0].Email)
+	s.Assert().NotZero(out[0].ID)
+	s.Assert().Equal(uint(1), out[1].ID)
+	s.Assert().Equal("Marcos Huck", out[1].Name)
+
+	out, err = members.UpsertBulk(ctx, nil)
+	s.Assert().NoError(err)
+	s.Assert().Empty(out)
+}
+
+func (s *SQLTestSuite) TestUpsertBulk_Normalized() {
+	type Subscriber struct {
+		ID    uint
+		Email string `gorm:"uniqueIndex;type:text collate nocase"`
+		Name  string
+	}
+	s.Require().NoError(s.tx.Migrator().AutoMigrate(&Subscriber{}))
+	ctx := context.Background()
+	subscribers := NewRepositorySQL[Subscriber, uint](s.tx, WithBatchSize(2))
+
+	_, err := subscribers.Create(ctx, Subscriber{Email: "marcos@example.com", Name: "Marcos"})
+	s.Require().NoError(err)
+
+	out, err := subscribers.UpsertBulk(ctx, []Subscriber{
+		{Email: "andres@example.com", Name: "Andres"},
+		{Email: "lucia@example.com", Name: "Lucia"},
+		{Email: "MARCOS@example.com", Name: "Marcos Huck"},
+	}, OnConflict("Email"), DoUpdate("Name"))
+	s.Require().NoError(err)
+	s.Require().Len(out, 3)
+	s.Assert().Equal("lucia@example.com", out[1].Email)
+	s.Assert().Equal(uint(1), out[2].ID)
+	s.Assert().Equal("marcos@example.com", out[2].Email)
+	s.Assert().Equal("Marcos Huck", out[2].Name)
+
+	_, err = subscribers.UpsertBulk(ctx, []Subscriber{
+		{Email: "julia@example.com"},
+		{Email: "ANDRES@example.com", Name: "Andres"},
+		{ID: 1, Email: "new@example.com"},
+	}, OnConflict("Email"), DoUpdate("Name"))
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	var count int64
+	s.Require().NoError(s.tx.Model(&Subscriber{}).Count(&count).Error)
+	s.Assert().Equal(int64(3), count)
+}
+
+func (s *SQLTestSuite) TestPatch_Mask() {
+	s.createMockData()
+	out, err := s.repository.Patch(context.Background(), 1, Mask(Test{FirstName: "Mark"}, "FirstName", "last_name"))
+	s.Require().NoError(err)
+	s.Assert().Equal(uint(1), out.ID)
+	s.Assert().Equal("Mark", out.FirstName)
+	s.Assert().Empty(out.LastName)
+
+	var stored Test
+	s.Require().NoError(s.tx.First(&stored, 1).Error)
+	s.Assert().Empty(stored.LastName)
+}
+
+func (s *SQLTestSuite) TestPatch_Fields() {
+	s.createMockData()
+	ctx := context.Background()
+	out, err := s.repository.Patch(ctx, 2, Fields[Test](map[string]interface{}{"first_name": "", "LastName": "Smith"}))
+	s.Require().NoError(err)
+	s.Assert().Empty(out.FirstName)
+	s.Assert().Equal("Smith", out.LastName)
+
+	members := NewRepositorySQL[Member, uint](s.tx)
+	_, err = members.Create(ctx, Member{Email: "marcos@example.com"})
+	s.Require().NoError(err)
+	member, err := members.Patch(ctx, 1, Fields[Member](map[string]interface{}{"name": "Marcos"}))
+	s.Require().NoError(err)
+	s.Assert().Equal("Marcos", member.Name)
+	_, err = members.Patch(ctx, 1, Fields[Member](map[string]interface{}{"id": 2, "name": "Marcos"}))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+	_, err = members.Patch(ctx, 1, Mask(Member{ID: 2}, "ID"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+
+	_, err = s.repository.Patch(ctx, 2, Fields[Test](map[string]interface{}{"first_name": 1}))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+
+	_, err = s.repository.Patch(ctx, 2, Fields[Test](map[string]interface{}{"first_name": nil}))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+
+	_, err = s.repository.Patch(ctx, 2, Fields[Test](map[string]interface{}{"deleted_at": nil}))
+	s.Assert().NoError(err)
+
+	_, err = s.repository.Patch(ctx, 2, Mask(Test{}, "unknown"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+}
+
+func (s *SQLTestSuite) TestPatch_NotFound() {
+	_, err := s.repository.Patch(context.Background(), 10, Mask(Test{}, "FirstName"))
+	s.Assert().ErrorIs(err, ErrNotFound)
+}
+
+func (s *SQLTestSuite) TestUpdate_NotFound() {
+	result, err := s.repository.Update(context.Background(), 10, Test{FirstName: "Changed"})
+	s.Assert().ErrorIs(err, ErrNotFound)
+	s.Assert().Zero(result)
+}
+
+func (s *SQLTestSuite) TestUpdateBulk() {
+	s.createMockData()
+	result, err := s.repository.UpdateBulk(context.Background(), []uint{1, 10, 2, 10}, Test{LastName: "Changed"})
+	s.Require().NoError(err)
+	s.Require().Len(result.Items, 2)
+	s.Assert().Equal("Changed", result.Items[0].LastName)
+	s.Assert().Equal("Changed", result.Items[1].LastName)
+	s.Assert().Equal([]uint{10}, result.Missing)
+}
+
+func (s *SQLTestSuite) TestRemoveBulk() {
+	s.createMockData()
+	result, err := s.repository.RemoveBulk(context.Background(), []uint{3, 4})
+	s.Require().NoError(err)
+	s.Require().Len(result.Items, 1)
+	s.Assert().Equal("Andrew", result.Items[0].FirstName)
+	s.Assert().Equal([]uint{4}, result.Missing)
+
+	result, err = s.repository.RemoveBulk(context.Background(), []uint{3})
+	s.Require().NoError(err)
+	s.Assert().Empty(result.Items)
+	s.Assert().Equal([]uint{3}, result.Missing)
+}
+
+func (s *SQLTestSuite) TestCreateBulk_Atomic() {
+	s.createMockData()
+	repository := NewRepositorySQL[Test, uint](s.tx, WithBatchSize(2))
+	_, err := repository.CreateBulk(context.Background(), []Test{
+		{FirstName: "John"},
+		{FirstName: "Jane"},
+		{Model: gorm.Model{ID: 1}, FirstName: "Conflict"},
+	})
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	var count int64
+	s.Require().NoError(s.tx.Model(&Test{}).Count(&count).Error)
+	s.Assert().Equal(int64(3), count)
+}
+
+func (s *SQLTestSuite) TestBulk_BatchSize() {
+	ctx := context.Background()
+	repository := NewRepositorySQL[Test, uint](s.tx, WithBatchSize(2))
+	created, err := repository.CreateBulk(ctx, []Test{{FirstName: "A"}, {FirstName: "B"}, {FirstName: "C"}, {FirstName: "D"}, {FirstName: "E"}})
+	s.Require().NoError(err)
+	s.Require().Len(created, 5)
+	s.Assert().Equal(uint(5), created[4].ID)
+
+	updated, err := repository.UpdateBulk(ctx, []uint{1, 2, 3, 4, 5, 6}, Test{LastName: "Batched"})
+	s.Require().NoError(err)
+	s.Require().Len(updated.Items, 5)
+	s.Assert().Equal([]uint{6}, updated.Missing)
+	for _, item := range updated.Items {
+		s.Assert().Equal("Batched", item.LastName, "entity %d", item.ID)
+	}
+	var stored []Test
+	s.Require().NoError(s.tx.Order("id").Find(&stored, []uint{1, 2, 3, 4, 5}).Error)
+	s.Require().Len(stored, 5)
+	for _, entity := range stored {
+		s.Assert().Equal("Batched", entity.LastName, "entity %d", entity.ID)
+	}
+
+	removed, err := repository.RemoveBulk(ctx, []uint{1, 2, 3, 4, 5})
+	s.Require().NoError(err)
+	s.Assert().Len(removed.Items, 5)
+	s.Assert().Empty(removed.Missing)
+}
+
+func (s *SQLTestSuite) TestBulk_BestEffort() {
+	s.createMockData()
+	ctx := context.Background()
+	repository := NewRepositorySQL[Test, uint](s.tx, WithBestEffort())
+
+	created, err := repository.CreateBulk(ctx, []Test{
+		{FirstName: "John"},
+		{Model: gorm.Model{ID: 1}, FirstName: "Conflict"},
+		{FirstName: "Jane"},
+	})
+	s.Assert().ErrorIs(err, ErrConflict)
+	var bulkErr *BulkError
+	s.Require().ErrorAs(err, &bulkErr)
+	s.Require().Len(bulkErr.Errors, 1)
+	s.Assert().Equal(1, bulkErr.Errors[0].Index)
+	s.Require().Len(created, 2)
+	s.Assert().Equal("John", created[0].FirstName)
+	s.Assert().Equal("Jane", created[1].FirstName)
+
+	updated, err := repository.UpdateBulk(ctx, []uint{1, 10}, Test{LastName: "Changed"})
+	s.Require().NoError(err)
+	s.Require().Len(updated.Items, 1)
+	s.Assert().Equal("Changed", updated.Items[0].LastName)
+	s.Assert().Equal([]uint{10}, updated.Missing)
+
+	removed, err := repository.RemoveBulk(ctx, []uint{1, 10})
+	s.Require().NoError(err)
+	s.Assert().Len(removed.Items, 1)
+	s.Assert().Equal([]uint{10}, removed.Missing)
+}
+
+func (s *SQLTestSuite) TestFindOrdered() {
+	s.createMockData()
+	repository := NewRepositorySQL[Test, uint](s.tx, WithBatchSize(2))
+	result, err := repository.FindOrdered(context.Background(), []uint{3, 10, 1, 3, 2, 20, 10})
+	s.Require().NoError(err)
+	s.Require().Len(result.Items, 3)
+	s.Assert().Equal(uint(3), result.Items[0].ID)
+	s.Assert().Equal(uint(1), result.Items[1].ID)
+	s.Assert().Equal(uint(2), result.Items[2].ID)
+	s.Assert().Equal([]uint{10, 20}, result.Missing)
+
+	result, err = repository.FindOrdered(context.Background(), nil)
+	s.Require().NoError(err)
+	s.Assert().Empty(result.Items)
+	s.Assert().Empty(result.Missing)
+}
+
+func (s *SQLTestSuite) TestEach() {
+	s.createMockData()
+	ctx := context.Background()
+	repository := NewRepositorySQL[Test, uint](s.tx, WithBatchSize(2))
+
+	var names []string
+	err := repository.Each(ctx, NewQuery(Eq("last_name", "Huck")).OrderBy(Desc("first_name")), func(entity Test) error {
+		names = append(names, entity.FirstName)
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"Marcos", "Andres"}, names)
+
+	var ids []uint
+	err = repository.Each(ctx, Query{}, func(entity Test) error {
+		ids = append(ids, entity.ID)
+		if len(ids) == 2 {
+			return ErrStop
+		}
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Assert().Equal([]uint{1, 2}, ids)
+
+	err = repository.Each(ctx, Query{}, func(entity Test) error {
+		return ErrConflict
+	})
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = repository.Each(canceled, Query{}, func(entity Test) error {
+		return nil
+	})
+	s.Assert().ErrorIs(err, context.Canceled)
+}
+
+func (s *SQLTestSuite) createAuthors() Repository[Author, uint] {
+	s.Require().NoError(s.tx.Create(&[]Author{
+		{
+			Name:    "Ursula",
+			Profile: AuthorProfile{Bio: "Earthsea"},
+			Books:   []Book{{Title: "A Wizard of Earthsea", Published: true}, {Title: "Draft"}},
+		},
+		{
+			Name:    "Octavia",
+			Profile: AuthorProfile{Bio: "Parables"},
+			Books:   []Book{{Title: "Kindred", Published: true}},
+		},
+	}).Error)
+	return NewRepositorySQL[Author, uint](s.tx)
+}
+
+func (s *SQLTestSuite) TestRead_Preload() {
+	authors := s.createAuthors()
+	ctx := context.Background()
+
+	author, err := authors.Get(ctx, 1)
+	s.Require().NoError(err)
+	s.Assert().Empty(author.Books)
+	s.Assert().Zero(author.Profile)
+
+	author, err = authors.Get(ctx, 1, WithPreload("Books", "Profile"))
+	s.Require().NoError(err)
+	s.Assert().Len(author.Books, 2)
+	s.Assert().Equal("Earthsea", author.Profile.Bio)
+
+	list, err := authors.List(ctx, Query{}.OrderBy(Asc("id")), WithPreloadFilter("Books", Eq("Published", true)))
+	s.Require().NoError(err)
+	s.Require().Len(list, 2)
+	s.Require().Len(list[0].Books, 1)
+	s.Assert().Equal("A Wizard of Earthsea", list[0].Books[0].Title)
+	s.Assert().Len(list[1].Books, 1)
+}
+
+func (s *SQLTestSuite) TestRead_Join() {
+	authors := s.createAuthors()
+	ctx := context.Background()
+
+	list, err := authors.Find(ctx, []uint{1, 2}, WithJoin("Profile"))
+	s.Require().NoError(err)
+	s.Require().Len(list, 2)
+	s.Assert().NotEmpty(list[0].Profile.Bio)
+	s.Assert().NotEmpty(list[1].Profile.Bio)
+
+	page, err := authors.Paginate(ctx, NewQuery(Eq("name", "Octavia")), Pagination{Keyset: true}, WithJoinFilter("Profile", Eq("bio", "Earthsea")))
+	s.Require().NoError(err)
+	s.Require().Len(page.Items, 1)
+	s.Assert().Equal("Octavia", page.Items[0].Name)
+	s.Assert().Zero(page.Items[0].Profile)
+}
+
+func (s *SQLTestSuite) TestRead_InvalidAssociation() {
+	authors := s.createAuthors()
+	ctx := context.Background()
+
+	_, err := authors.Get(ctx, 1, WithPreload("Bookz"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+
+	_, err = authors.Get(ctx, 1, WithPreload("Profile.Address"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+
+	_, err = authors.Get(ctx, 1, WithPreloadFilter("Books", Eq("unknown", 1)))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+
+	_, err = authors.Get(ctx, 1, WithJoin("Books"))
+	s.Assert().ErrorIs(err, ErrInvalidField)
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// onConflict translates the given upsert options into an ON CONFLICT clause. It also returns the fields
+// identifying conflicting entities.
+func (r *SQL[E, K]) onConflict(o upsertOptions) (clause.OnConflict, []*schema.Field, error) {
+	fields := []*schema.Field{r.pk}
+	if len(o.conflict) > 0 {
+		fields = make([]*schema.Field, len(o.conflict))
+		for i, name := range o.conflict {
+			field, err := lookUpField(r.schema, name)
+			if err != nil {
+				return clause.OnConflict{}, nil, err
+			}
+			fields[i] = field
+		}
+	}
+
+	onConflict := clause.OnConflict{Columns: make([]clause.Column, len(fields))}
+	for i, field := range fields {
+		onConflict.Columns[i] = clause.Column{Name: field.DBName}
+	}
+	switch {
+	case o.nothing:
+		onConflict.DoNothing = true
+	case len(o.update) > 0:
+		columns := make([]string, len(o.update))
+		for i, name := range o.update {
+			field, err := lookUpField(r.schema, name)
+			if err != nil {
+				return clause.OnConflict{}, nil, err
+			}
+			columns[i] = field.DBName
+		}
+		onConflict.DoUpdates = clause.AssignmentColumns(columns)
+	default:
+		onConflict.UpdateAll = true
+	}
+	return onConflict, fields, nil
+}
+
+// reload returns the persisted version of the given entities, looking them up by the values of the given fields.
+// Entities are returned in the same order. Rows are matched to entities by comparing their values, and the entities
+// without an identical row, such as those whose values were normalized by a case-insensitive collation, are looked
+// up one by one so the database decides which row they conflicted with. It returns ErrNotFound if any of the
+// entities doesn't exist.
+func (r *SQL[E, K]) reload(ctx context.Context, entities []E, fields []*schema.Field) ([]E, error) {
+	keys := make([]string, len(entities))
+	filters := make([]Filter, len(entities))
+	for i := range entities {
+		keys[i], filters[i] = fieldsKey(ctx, reflect.ValueOf(&entities[i]).Elem(), fields)
+	}
+
+	db, err := r.model(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if db, err = r.applyQuery(db, NewQuery(Or(filters...))); err != nil {
+		return nil, err
+	}
+	var rows []E
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	persisted := make(map[string]E, len(rows))
+	for i := range rows {
+		key, _ := fieldsKey(ctx, reflect.ValueOf(&rows[i]).Elem(), fields)
+		persisted[key] = rows[i]
+	}
+	out := make([]E, len(entities))
+	for i, key := range keys {
+		row, ok := persisted[key]
+		if !ok {
+			if row, err = r.lookUp(ctx, filters[i]); err != nil {
+				return nil, fmt.Errorf("%w: %s", err, key)
+			}
+		}
+		out[i] = row
+	}
+	return out, nil
+}
+
+// lookUp returns the entity matching the given filter. It returns ErrNotFound if no entity matches it.
+func (r *SQL[E, K]) lookUp(ctx context.Context, f Filter) (E, error) {
+	var out E
+	db, err := r.model(ctx)
+	if err != nil {
+		return out, err
+	}
+	if db, err = r.applyQuery(db, NewQuery(f)); err != nil {
+		return out, err
+	}
+	if err := db.Take(&out).Error; err != nil {
+		var zero E
+		return zero, translateError(err)
+	}
+	return out, nil
+}
+
+// fieldsKey returns a key identifying the values of the given fields of an entity, and a Filter matching them.
+func fieldsKey(ctx context.Context, v reflect.Value, fields []*schema.Field) (string, Filter) {
+	values := make([]interface{}, len(fields))
+	and := make([]Filter, len(fields))
+	for i, field := range fields {
+		values[i], _ = field.ValueOf(ctx, v)
+		and[i] = Eq(field.DBName, values[i])
+	}
+	return fmt.Sprintf("%#v", values), And(and...)
+}
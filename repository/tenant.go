@@ -0,0 +1,443 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sync"
+)
+
+// tenantKey is the context key holding the current tenant.
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx holding the given tenant, which is read by repositories wrapped using
+// WithTenant.
+func ContextWithTenant[T comparable](ctx context.Context, tenant T) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant held by ctx. The boolean is false if ctx has no tenant of type T.
+func TenantFromContext[T comparable](ctx context.Context) (T, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(T)
+	return tenant, ok
+}
+
+// tenantScoped is a Repository restricting every operation to the entities of the tenant held by the context.
+// It doesn't embed the wrapped Repository, so new methods cannot bypass the tenant conditions.
+type tenantScoped[E any, K comparable, T comparable] struct {
+	repository Repository[E, K]
+	field      string
+
+	once      sync.Once
+	schema    *schema.Schema
+	pk        *schema.Field
+	tenant    *schema.Field
+	deletedAt *schema.Field
+	err       error
+}
+
+// parse parses the schema of the entity, resolving its primary key and tenant fields. The schema is only parsed
+// once.
+func (r *tenantScoped[E, K, T]) parse() error {
+	r.once.Do(func() {
+		if r.schema, r.err = schema.Parse(new(E), &sync.Map{}, schema.NamingStrategy{}); r.err != nil {
+			return
+		}
+		if r.pk, r.err = primaryKey(r.schema, ""); r.err != nil {
+			return
+		}
+		r.deletedAt = softDeleteField(r.schema)
+		r.tenant, r.err = lookUpField(r.schema, r.field)
+	})
+	return r.err
+}
+
+// current returns the tenant held by ctx. It returns ErrMissingTenant if ctx has no tenant, or if the tenant is
+// the zero value, which identifies entities without tenant.
+func (r *tenantScoped[E, K, T]) current(ctx context.Context) (T, error) {
+	var zero T
+	if err := r.parse(); err != nil {
+		return zero, err
+	}
+	tenant, ok := TenantFromContext[T](ctx)
+	if !ok || tenant == zero {
+		return zero, fmt.Errorf("%w: %s", ErrMissingTenant, r.schema.Name)
+	}
+	return tenant, nil
+}
+
+// tenantOf returns the tenant of the given entity.
+func (r *tenantScoped[E, K, T]) tenantOf(ctx context.Context, entity *E) T {
+	return primaryKeyValue[T](ctx, r.tenant, reflect.ValueOf(entity).Elem())
+}
+
+// primaryKeyOf returns the primary key of the given entity.
+func (r *tenantScoped[E, K, T]) primaryKeyOf(ctx context.Context, entity *E) K {
+	return primaryKeyValue[K](ctx, r.pk, reflect.ValueOf(entity).Elem())
+}
+
+// stamp sets the tenant of an entity without tenant. It returns ErrTenantMismatch if the entity belongs to a
+// different tenant.
+func (r *tenantScoped[E, K, T]) stamp(ctx context.Context, tenant T, entity *E) error {
+	var zero T
+	switch r.tenantOf(ctx, entity) {
+	case tenant:
+		return nil
+	case zero:
+		return r.tenant.Set(ctx, reflect.ValueOf(entity).Elem(), tenant)
+	default:
+		return fmt.Errorf("%w: %s", ErrTenantMismatch, r.schema.Name)
+	}
+}
+
+// stampAll returns a copy of entities with their tenant set.
+func (r *tenantScoped[E, K, T]) stampAll(ctx context.Context, tenant T, entities []E) ([]E, error) {
+	out := make([]E, len(entities))
+	copy(out, entities)
+	for i := range out {
+		if err := r.stamp(ctx, tenant, &out[i]); err != nil {
+			return nil, ItemError{Index: i, Err: err}
+		}
+	}
+	return out, nil
+}
+
+// scope returns a copy of ctx restricting the operations of the wrapped repository to the entities of the given
+// tenant. The tenant condition is added to every statement run by SQL and Memory on the entities of E, but not to
+// the ones of other repositories receiving ctx, such as those used by hooks.
+func (r *tenantScoped[E, K, T]) scope(ctx context.Context, tenant T) context.Context {
+	return contextWithScope(ctx, r.schema, Eq(r.tenant.Name, tenant))
+}
+
+// notFound returns the error returned for entities that don't exist or belong to a different tenant, which are
+// indistinguishable to callers.
+func (r *tenantScoped[E, K, T]) notFound(id K) error {
+	return fmt.Errorf("%w: %s %v", ErrNotFound, r.schema.Name, id)
+}
+
+// trashed returns the read options reading soft deleted entities, if the entity can be soft deleted.
+func (r *tenantScoped[E, K, T]) trashed() []ReadOption {
+	if r.deletedAt == nil {
+		return nil
+	}
+	return []ReadOption{WithTrashed()}
+}
+
+// checkUpsert returns ErrConflict if an entity with the same primary key belongs to a different tenant. The entity
+// is read without the tenant condition, so ctx must not be scoped.
+func (r *tenantScoped[E, K, T]) checkUpsert(ctx context.Context, tenant T, entity *E) error {
+	var zero K
+	id := r.primaryKeyOf(ctx, entity)
+	if id == zero {
+		return nil
+	}
+	existing, err := r.repository.Get(ctx, id, r.trashed()...)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if r.tenantOf(ctx, &existing) != tenant {
+		return fmt.Errorf("%w: %s %v", ErrConflict, r.schema.Name, id)
+	}
+	return nil
+}
+
+// filter returns the entities of items belonging to the given tenant. Reads are already scoped, it only guards
+// against wrapped repositories returning entities they cached regardless of the scope.
+func (r *tenantScoped[E, K, T]) filter(ctx context.Context, tenant T, items []E) []E {
+	owned := make([]E, 0, len(items))
+	for i := range items {
+		if r.tenantOf(ctx, &items[i]) == tenant {
+			owned = append(owned, items[i])
+		}
+	}
+	return owned
+}
+
+// missing returns the result of a bulk operation on ids, where only the entities in result were found.
+func (r *tenantScoped[E, K, T]) missing(ctx context.Context, ids []K, result Result[E, K]) Result[E, K] {
+	found := make(map[K]bool, len(result.Items))
+	for i := range result.Items {
+		found[r.primaryKeyOf(ctx, &result.Items[i])] = true
+	}
+	result.Missing = nil
+	for _, id := range unique(ids) {
+		if !found[id] {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+	return result
+}
+
+// Create creates an entity, setting its tenant. It returns ErrTenantMismatch if the entity belongs to a different
+// tenant.
+func (r *tenantScoped[E, K, T]) Create(ctx context.Context, entity E) (E, error) {
+	var zero E
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return zero, err
+	}
+	if err := r.stamp(ctx, tenant, &entity); err != nil {
+		return zero, err
+	}
+	return r.repository.Create(r.scope(ctx, tenant), entity)
+}
+
+// CreateBulk creates a set of entities, setting their tenant. It returns ErrTenantMismatch if any entity belongs
+// to a different tenant.
+func (r *tenantScoped[E, K, T]) CreateBulk(ctx context.Context, entities []E) ([]E, error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if entities, err = r.stampAll(ctx, tenant, entities); err != nil {
+		return nil, err
+	}
+	return r.repository.CreateBulk(r.scope(ctx, tenant), entities)
+}
+
+// Upsert creates or updates an entity, setting its tenant. It returns ErrConflict if an entity with the same
+// primary key belongs to a different tenant.
+func (r *tenantScoped[E, K, T]) Upsert(ctx context.Context, entity E, opts ...UpsertOption) (E, error) {
+	var zero E
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return zero, err
+	}
+	if err := r.stamp(ctx, tenant, &entity); err != nil {
+		return zero, err
+	}
+	if err := r.checkUpsert(ctx, tenant, &entity); err != nil {
+		return zero, err
+	}
+	return r.repository.Upsert(r.scope(ctx, tenant), entity, opts...)
+}
+
+// UpsertBulk creates or updates a set of entities, setting their tenant. It returns ErrConflict if an entity with
+// the same primary key as any of the given entities belongs to a different tenant.
+func (r *tenantScoped[E, K, T]) UpsertBulk(ctx context.Context, entities []E, opts ...UpsertOption) ([]E, error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if entities, err = r.stampAll(ctx, tenant, entities); err != nil {
+		return nil, err
+	}
+	for i := range entities {
+		if err := r.checkUpsert(ctx, tenant, &entities[i]); err != nil {
+			return nil, ItemError{Index: i, Err: err}
+		}
+	}
+	return r.repository.UpsertBulk(r.scope(ctx, tenant), entities, opts...)
+}
+
+// Get returns an entity of the current tenant identified by its ID. It returns ErrNotFound if the entity doesn't
+// exist or belongs to a different tenant.
+func (r *tenantScoped[E, K, T]) Get(ctx context.Context, id K, opts ...ReadOption) (E, error) {
+	var zero E
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return zero, err
+	}
+	entity, err := r.repository.Get(r.scope(ctx, tenant), id, opts...)
+	if err != nil {
+		return zero, err
+	}
+	if r.tenantOf(ctx, &entity) != tenant {
+		return zero, r.notFound(id)
+	}
+	return entity, nil
+}
+
+// Find returns the entities of the current tenant identified by the given IDs.
+func (r *tenantScoped[E, K, T]) Find(ctx context.Context, ids []K, opts ...ReadOption) ([]E, error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := r.repository.Find(r.scope(ctx, tenant), ids, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return r.filter(ctx, tenant, list), nil
+}
+
+// FindOrdered returns the entities of the current tenant identified by the given IDs, in the same order. The IDs
+// of entities belonging to a different tenant are reported as missing.
+func (r *tenantScoped[E, K, T]) FindOrdered(ctx context.Context, ids []K, opts ...ReadOption) (Result[E, K], error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	result, err := r.repository.FindOrdered(r.scope(ctx, tenant), ids, opts...)
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	result.Items = r.filter(ctx, tenant, result.Items)
+	return r.missing(ctx, ids, result), nil
+}
+
+// List returns the entities of the current tenant matching the given Query.
+func (r *tenantScoped[E, K, T]) List(ctx context.Context, query Query, opts ...ReadOption) ([]E, error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.repository.List(r.scope(ctx, tenant), query, opts...)
+}
+
+// Paginate returns a page of the entities of the current tenant matching the given Query.
+func (r *tenantScoped[E, K, T]) Paginate(ctx context.Context, query Query, pagination Pagination, opts ...ReadOption) (Page[E], error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return Page[E]{}, err
+	}
+	return r.repository.Paginate(r.scope(ctx, tenant), query, pagination, opts...)
+}
+
+// Each calls fn with every entity of the current tenant matching the given Query.
+func (r *tenantScoped[E, K, T]) Each(ctx context.Context, query Query, fn func(entity E) error, opts ...ReadOption) error {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return err
+	}
+	return r.repository.Each(r.scope(ctx, tenant), query, fn, opts...)
+}
+
+// Update updates an entity of the current tenant. It returns ErrNotFound if the entity belongs to a different
+// tenant, and ErrTenantMismatch if entity sets a different tenant.
+func (r *tenantScoped[E, K, T]) Update(ctx context.Context, id K, entity E) (E, error) {
+	var zero E
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return zero, err
+	}
+	if err := r.stamp(ctx, tenant, &entity); err != nil {
+		return zero, err
+	}
+	return r.repository.Update(r.scope(ctx, tenant), id, entity)
+}
+
+// Patch updates the fields of an entity of the current tenant listed in patch. It returns ErrNotFound if the
+// entity belongs to a different tenant, and ErrTenantMismatch if the patch sets a different tenant.
+func (r *tenantScoped[E, K, T]) Patch(ctx context.Context, id K, patch Patch[E]) (E, error) {
+	var zero E
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return zero, err
+	}
+	mask, err := patch.mask(ctx, r.schema)
+	if err != nil {
+		return zero, err
+	}
+	for _, name := range mask.fields {
+		if name == r.tenant.Name && r.tenantOf(ctx, &mask.entity) != tenant {
+			return zero, fmt.Errorf("%w: %s", ErrTenantMismatch, r.schema.Name)
+		}
+	}
+	return r.repository.Patch(r.scope(ctx, tenant), id, patch)
+}
+
+// UpdateBulk updates the entities of the current tenant identified by ids. The IDs of entities belonging to a
+// different tenant are reported as missing.
+func (r *tenantScoped[E, K, T]) UpdateBulk(ctx context.Context, ids []K, entity E) (Result[E, K], error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	if err := r.stamp(ctx, tenant, &entity); err != nil {
+		return Result[E, K]{}, err
+	}
+	return r.repository.UpdateBulk(r.scope(ctx, tenant), ids, entity)
+}
+
+// Remove removes an entity of the current tenant. It returns ErrNotFound if the entity belongs to a different
+// tenant.
+func (r *tenantScoped[E, K, T]) Remove(ctx context.Context, id K) (E, error) {
+	var zero E
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return zero, err
+	}
+	return r.repository.Remove(r.scope(ctx, tenant), id)
+}
+
+// RemoveBulk removes the entities of the current tenant identified by ids. The IDs of entities belonging to a
+// different tenant are reported as missing.
+func (r *tenantScoped[E, K, T]) RemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	return r.repository.RemoveBulk(r.scope(ctx, tenant), ids)
+}
+
+// Restore restores a soft deleted entity of the current tenant. It returns ErrNotFound if the entity belongs to a
+// different tenant.
+func (r *tenantScoped[E, K, T]) Restore(ctx context.Context, id K) (E, error) {
+	var zero E
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return zero, err
+	}
+	return r.repository.Restore(r.scope(ctx, tenant), id)
+}
+
+// RestoreBulk restores the soft deleted entities of the current tenant identified by ids. The IDs of entities
+// belonging to a different tenant are reported as missing.
+func (r *tenantScoped[E, K, T]) RestoreBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	return r.repository.RestoreBulk(r.scope(ctx, tenant), ids)
+}
+
+// ForceRemove permanently removes an entity of the current tenant. It returns ErrNotFound if the entity belongs to
+// a different tenant.
+func (r *tenantScoped[E, K, T]) ForceRemove(ctx context.Context, id K) (E, error) {
+	var zero E
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return zero, err
+	}
+	return r.repository.ForceRemove(r.scope(ctx, tenant), id)
+}
+
+// ForceRemoveBulk permanently removes the entities of the current tenant identified by ids. The IDs of entities
+// belonging to a different tenant are reported as missing.
+func (r *tenantScoped[E, K, T]) ForceRemoveBulk(ctx context.Context, ids []K) (Result[E, K], error) {
+	tenant, err := r.current(ctx)
+	if err != nil {
+		return Result[E, K]{}, err
+	}
+	return r.repository.ForceRemoveBulk(r.scope(ctx, tenant), ids)
+}
+
+// WithTenant wraps repository, restricting every operation to the entities of the tenant held by the context,
+// which is set using ContextWithTenant. The given field, either a struct field name or a column name, holds the
+// tenant of every entity, and its type must be T. Operations return ErrMissingTenant if the context has no tenant,
+// or if its tenant is the zero value of T.
+//
+// Entities of other tenants are reported as not found by reads, updates and removals. Creating an entity sets its
+// tenant, and creating or updating an entity that sets a different tenant returns ErrTenantMismatch.
+//
+// The tenant condition is added to the statements run by the wrapped repository, which must be created using
+// NewRepositorySQL or NewMemory, optionally wrapped by the other wrappers of this package.
+//
+// Upserts are checked against the primary key of the entities only, so unique indexes used by upserts must include
+// the tenant field to prevent them from matching entities of other tenants.
+//
+//	documents := repository.WithTenant[Document, uint, string](repository.NewRepositorySQL[Document, uint](db), "tenant_id")
+//	ctx = repository.ContextWithTenant(ctx, "acme")
+//	list, err := documents.List(ctx, repository.Query{})
+func WithTenant[E any, K comparable, T comparable](repository Repository[E, K], field string) Repository[E, K] {
+	return &tenantScoped[E, K, T]{
+		repository: repository,
+		field:      field,
+	}
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"path/filepath"
+	"testing"
+)
+
+type Document struct {
+	ID       uint
+	TenantID string
+	Title    string
+}
+
+func TestTenant(t *testing.T) {
+	suite.Run(t, &TenantTestSuite{newBackend: func(t *testing.T) Repository[Document, uint] {
+		return NewMemory[Document, uint]()
+	}})
+}
+
+func TestTenantSQL(t *testing.T) {
+	suite.Run(t, &TenantTestSuite{newBackend: func(t *testing.T) Repository[Document, uint] {
+		return NewRepositorySQL[Document, uint](openTenantDB(t))
+	}})
+}
+
+func TestTenantSQL_Statements(t *testing.T) {
+	db := openTenantDB(t)
+	var statements []string
+	capture := func(db *gorm.DB) {
+		statements = append(statements, db.Statement.SQL.String())
+	}
+	assert.NoError(t, db.Callback().Update().After("gorm:update").Register("test:capture", capture))
+	assert.NoError(t, db.Callback().Delete().After("gorm:delete").Register("test:capture", capture))
+
+	backend := NewRepositorySQL[Document, uint](db)
+	_, err := backend.CreateBulk(context.Background(), []Document{{TenantID: "acme"}, {TenantID: "globex"}})
+	assert.NoError(t, err)
+
+	documents := WithTenant[Document, uint, string](backend, "tenant_id")
+	ctx := ContextWithTenant(context.Background(), "acme")
+	_, err = documents.Update(ctx, 2, Document{Title: "Stolen"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = documents.Remove(ctx, 2)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = documents.RemoveBulk(ctx, []uint{1, 2})
+	assert.NoError(t, err)
+
+	assert.Len(t, statements, 2)
+	for _, statement := range statements {
+		assert.Contains(t, statement, "tenant_id")
+	}
+	entity, err := backend.Get(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Empty(t, entity.Title)
+}
+
+func TestTenantSQL_Hooks(t *testing.T) {
+	type Widget struct {
+		ID   uint
+		Name string
+	}
+	db := openTenantDB(t)
+	assert.NoError(t, db.AutoMigrate(&Widget{}))
+	widgets := NewRepositorySQL[Widget, uint](db)
+	_, err := widgets.Create(context.Background(), Widget{Name: "Gear"})
+	assert.NoError(t, err)
+	labels := NewMemory[Widget, uint]()
+	_, err = labels.Create(context.Background(), Widget{Name: "Label"})
+	assert.NoError(t, err)
+
+	var names []string
+	documents := WithTenant[Document, uint, string](WithHooks(NewRepositorySQL[Document, uint](db), Hooks[Document, uint]{
+		AfterCreate: func(ctx context.Context, entity Document) error {
+			for _, repository := range []Repository[Widget, uint]{widgets, labels} {
+				widget, err := repository.Get(ctx, 1)
+				if err != nil {
+					return err
+				}
+				names = append(names, widget.Name)
+			}
+			return nil
+		},
+	}), "tenant_id")
+
+	created, err := documents.Create(ContextWithTenant(context.Background(), "acme"), Document{Title: "Plans"})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", created.TenantID)
+	assert.Equal(t, []string{"Gear", "Label"}, names)
+}
+
+// openTenantDB opens an SQLite database holding the documents table.
+func openTenantDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "tenant_test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Document{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+type TenantTestSuite struct {
+	suite.Suite
+
+	newBackend func(t *testing.T) Repository[Document, uint]
+	backend    Repository[Document, uint]
+	repository Repository[Document, uint]
+	acme       context.Context
+	globex     context.Context
+}
+
+func (s *TenantTestSuite) SetupTest() {
+	s.backend = s.newBackend(s.T())
+	s.repository = WithTenant[Document, uint, string](s.backend, "tenant_id")
+	s.acme = ContextWithTenant(context.Background(), "acme")
+	s.globex = ContextWithTenant(context.Background(), "globex")
+
+	_, err := s.backend.CreateBulk(context.Background(), []Document{
+		{TenantID: "acme", Title: "Plans"},
+		{TenantID: "globex", Title: "Secrets"},
+		{TenantID: "acme", Title: "Budget"},
+	})
+	s.Require().NoError(err)
+}
+
+func (s *TenantTestSuite) TestMissingTenant() {
+	_, err := s.repository.Get(context.Background(), 1)
+	s.Assert().ErrorIs(err, ErrMissingTenant)
+	_, err = s.repository.List(context.Background(), Query{})
+	s.Assert().ErrorIs(err, ErrMissingTenant)
+	_, err = s.repository.Create(ContextWithTenant(context.Background(), 1), Document{})
+	s.Assert().ErrorIs(err, ErrMissingTenant)
+
+	empty := ContextWithTenant(context.Background(), "")
+	_, err = s.repository.Create(empty, Document{Title: "Orphan"})
+	s.Assert().ErrorIs(err, ErrMissingTenant)
+	_, err = s.repository.List(empty, Query{})
+	s.Assert().ErrorIs(err, ErrMissingTenant)
+}
+
+func (s *TenantTestSuite) TestCreate() {
+	created, err := s.repository.Create(s.acme, Document{Title: "Roadmap"})
+	s.Require().NoError(err)
+	s.Assert().Equal("acme", created.TenantID)
+
+	_, err = s.repository.Create(s.acme, Document{TenantID: "globex"})
+	s.Assert().ErrorIs(err, ErrTenantMismatch)
+
+	entities := []Document{{Title: "A"}, {TenantID: "globex", Title: "B"}}
+	_, err = s.repository.CreateBulk(s.acme, entities)
+	s.Assert().ErrorIs(err, ErrTenantMismatch)
+	s.Assert().Empty(entities[0].TenantID)
+
+	list, err := s.repository.CreateBulk(s.globex, []Document{{Title: "A"}, {TenantID: "globex", Title: "B"}})
+	s.Require().NoError(err)
+	s.Assert().Equal("globex", list[0].TenantID)
+}
+
+func (s *TenantTestSuite) TestUpsert() {
+	_, err := s.repository.Upsert(s.acme, Document{ID: 2, Title: "Stolen"})
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	upserted, err := s.repository.Upsert(s.acme, Document{ID: 1, Title: "New plans"})
+	s.Require().NoError(err)
+	s.Assert().Equal("acme", upserted.TenantID)
+
+	_, err = s.repository.UpsertBulk(s.globex, []Document{{ID: 2, Title: "Other"}, {ID: 3}})
+	s.Assert().ErrorIs(err, ErrConflict)
+
+	entity, err := s.backend.Get(context.Background(), 2)
+	s.Require().NoError(err)
+	s.Assert().Equal("Secrets", entity.Title)
+}
+
+func (s *TenantTestSuite) TestRead() {
+	result, err := s.repository.Get(s.acme, 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("Plans", result.Title)
+
+	_, err = s.repository.Get(s.acme, 2)
+	s.Assert().ErrorIs(err, ErrNotFound)
+
+	list, err := s.repository.Find(s.acme, []uint{1, 2, 3})
+	s.Require().NoError(err)
+	s.Assert().Len(list, 2)
+
+	ordered, err := s.repository.FindOrdered(s.acme, []uint{3, 2, 4, 1})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"Budget", "Plans"}, []string{ordered.Items[0].Title, ordered.Items[1].Title})
+	s.Assert().Equal([]uint{2, 4}, ordered.Missing)
+
+	list, err = s.repository.List(s.globex, Query{})
+	s.Require().NoError(err)
+	s.Require().Len(list, 1)
+	s.Assert().Equal("Secrets", list[0].Title)
+
+	page, err := s.repository.Paginate(s.acme, NewQuery(Like("title", "%")), Pagination{Size: 1, Total: true})
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(2), *page.Total)
+
+	var titles []string
+	err = s.repository.Each(s.acme, Query{}, func(entity Document) error {
+		titles = append(titles, entity.Title)
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"Plans", "Budget"}, titles)
+}
+
+func (s *TenantTestSuite) TestUpdate() {
+	_, err := s.repository.Update(s.acme, 2, Document{Title: "Stolen"})
+	s.Assert().ErrorIs(err, ErrNotFound)
+	_, err = s.repository.Update(s.acme, 1, Document{TenantID: "globex"})
+	s.Assert().ErrorIs(err, ErrTenantMismatch)
+	updated, err := s.repository.Update(s.acme, 1, Document{Title: "New plans"})
+	s.Require().NoError(err)
+	s.Assert().Equal("New plans", updated.Title)
+
+	_, err = s.repository.Patch(s.acme, 2, Mask(Document{}, "Title"))
+	s.Assert().ErrorIs(err, ErrNotFound)
+	_, err = s.repository.Patch(s.acme, 1, Fields[Document](map[string]interface{}{"tenant_id": "globex"}))
+	s.Assert().ErrorIs(err, ErrTenantMismatch)
+	patched, err := s.repository.Patch(s.acme, 1, Mask(Document{}, "Title"))
+	s.Require().NoError(err)
+	s.Assert().Empty(patched.Title)
+
+	result, err := s.repository.UpdateBulk(s.acme, []uint{1, 2, 3, 4}, Document{Title: "Bulk"})
+	s.Require().NoError(err)
+	s.Assert().Len(result.Items, 2)
+	s.Assert().Equal([]uint{2, 4}, result.Missing)
+
+	entity, err := s.backend.Get(context.Background(), 2)
+	s.Require().NoError(err)
+	s.Assert().Equal("Secrets", entity.Title)
+}
+
+func (s *TenantTestSuite) TestRemove() {
+	_, err := s.repository.Remove(s.acme, 2)
+	s.Assert().ErrorIs(err, ErrNotFound)
+	removed, err := s.repository.Remove(s.acme, 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("Plans", removed.Title)
+
+	result, err := s.repository.RemoveBulk(s.globex, []uint{1, 2, 3})
+	s.Require().NoError(err)
+	s.Require().Len(result.Items, 1)
+	s.Assert().Equal("Secrets", result.Items[0].Title)
+	s.Assert().Equal([]uint{1, 3}, result.Missing)
+
+	list, err := s.backend.List(context.Background(), Query{})
+	s.Require().NoError(err)
+	s.Assert().Len(list, 1)
+}
+
+func (s *TenantTestSuite) TestForceRemove() {
+	_, err := s.repository.ForceRemove(s.acme, 2)
+	s.Assert().ErrorIs(err, ErrNotFound)
+	_, err = s.repository.Restore(s.acme, 1)
+	s.Assert().ErrorIs(err, ErrNotSoftDeletable)
+
+	result, err := s.repository.ForceRemoveBulk(s.acme, []uint{1, 2, 3})
+	s.Require().NoError(err)
+	s.Assert().Len(result.Items, 2)
+	s.Assert().Equal([]uint{2}, result.Missing)
+
+	list, err := s.backend.List(context.Background(), Query{})
+	s.Require().NoError(err)
+	s.Require().Len(list, 1)
+	s.Assert().Equal("Secrets", list[0].Title)
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"sync"
+)
+
+// UnitOfWork runs operations on repositories of different entities as a single transaction.
+//
+//	uow := repository.NewUnitOfWork(db)
+//	err := uow.Do(ctx, func(ctx context.Context, tx *repository.Tx) error {
+//		order, err := repository.Use[Order, uint](tx).Create(ctx, order)
+//		if err != nil {
+//			return err
+//		}
+//		if _, err := repository.Use[Cart, uint](tx).Remove(ctx, cartID); err != nil {
+//			return err
+//		}
+//		tx.AfterCommit(func(ctx context.Context) {
+//			notify(order)
+//		})
+//		return nil
+//	})
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+// Do runs fn in a transaction, which is committed if fn returns nil and rolled back otherwise, including when fn
+// panics. The callbacks registered using Tx.AfterCommit are called once the transaction is committed, in the same
+// order they were registered. It returns the error returned by fn, or the error committing the transaction.
+//
+// If the UnitOfWork database already runs in a transaction, a savepoint is used instead. When that transaction was
+// started by another UnitOfWork, and ctx is the context it passed to its function, the callbacks are deferred
+// until the outermost transaction is committed. Otherwise, they're called once the savepoint is released.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	var tx *Tx
+	err := u.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		tx = &Tx{db: db}
+		return fn(context.WithValue(ctx, txContextKey{}, tx), tx)
+	})
+	if err != nil {
+		return err
+	}
+	if parent, ok := ctx.Value(txContextKey{}).(*Tx); ok && u.nested() {
+		for _, callback := range tx.callbacks() {
+			parent.AfterCommit(callback)
+		}
+		return nil
+	}
+	for _, callback := range tx.callbacks() {
+		callback(ctx)
+	}
+	return nil
+}
+
+// nested returns true if the UnitOfWork database already runs in a transaction.
+func (u *UnitOfWork) nested() bool {
+	committer, ok := u.db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && committer != nil
+}
+
+// NewUnitOfWork initializes a new UnitOfWork running transactions in db.
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{
+		db: db,
+	}
+}
+
+// txContextKey is the context key of the Tx run by a UnitOfWork.
+type txContextKey struct{}
+
+// Tx is a transaction run by a UnitOfWork. It's only valid while the function passed to UnitOfWork.Do runs.
+type Tx struct {
+	db *gorm.DB
+
+	mu          sync.Mutex
+	afterCommit []func(ctx context.Context)
+}
+
+// AfterCommit registers fn to be called after the transaction is committed. It's not called if the transaction is
+// rolled back.
+func (tx *Tx) AfterCommit(fn func(ctx context.Context)) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.afterCommit = append(tx.afterCommit, fn)
+}
+
+// callbacks returns the registered after commit callbacks.
+func (tx *Tx) callbacks() []func(ctx context.Context) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.afterCommit
+}
+
+// Use returns a Repository bound to the given transaction, configured with opts. The returned repository must not
+// be used once the transaction is done.
+func Use[E any, K comparable](tx *Tx, opts ...Option) Repository[E, K] {
+	return NewRepositorySQL[E, K](tx.db, opts...)
+}
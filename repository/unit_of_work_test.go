@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	suite.Run(t, new(UnitOfWorkTestSuite))
+}
+
+type UnitOfWorkTestSuite struct {
+	suite.Suite
+
+	db  *gorm.DB
+	uow *UnitOfWork
+}
+
+func (s *UnitOfWorkTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "uow_test.db")))
+	s.Require().NoError(err)
+	s.Require().NoError(db.AutoMigrate(&Test{}, &Account{}))
+	s.db = db
+	s.uow = NewUnitOfWork(db)
+}
+
+func (s *UnitOfWorkTestSuite) TearDownTest() {
+	sqlDB, err := s.db.DB()
+	s.Require().NoError(err)
+	s.Require().NoError(sqlDB.Close())
+}
+
+func (s *UnitOfWorkTestSuite) count(model interface{}) int64 {
+	var count int64
+	s.Require().NoError(s.db.Model(model).Count(&count).Error)
+	return count
+}
+
+func (s *UnitOfWorkTestSuite) TestDo() {
+	var calls []string
+	err := s.uow.Do(context.Background(), func(ctx context.Context, tx *Tx) error {
+		tests := Use[Test, uint](tx)
+		created, err := tests.Create(ctx, Test{FirstName: "Marcos"})
+		if err != nil {
+			return err
+		}
+		if _, err := Use[Account, string](tx).Create(ctx, Account{UUID: "1234", Name: "Marcos"}); err != nil {
+			return err
+		}
+		if _, err := tests.Update(ctx, created.ID, Test{LastName: "Huck"}); err != nil {
+			return err
+		}
+		tx.AfterCommit(func(ctx context.Context) {
+			calls = append(calls, "first")
+		})
+		tx.AfterCommit(func(ctx context.Context) {
+			calls = append(calls, "second")
+		})
+		s.Assert().Empty(calls)
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"first", "second"}, calls)
+	s.Assert().Equal(int64(1), s.count(&Test{}))
+	s.Assert().Equal(int64(1), s.count(&Account{}))
+
+	result, err := NewRepositorySQL[Test, uint](s.db).Get(context.Background(), 1)
+	s.Require().NoError(err)
+	s.Assert().Equal("Huck", result.LastName)
+}
+
+func (s *UnitOfWorkTestSuite) TestDo_Rollback() {
+	called := false
+	err := s.uow.Do(context.Background(), func(ctx context.Context, tx *Tx) error {
+		tx.AfterCommit(func(ctx context.Context) {
+			called = true
+		})
+		if _, err := Use[Test, uint](tx).Create(ctx, Test{FirstName: "Marcos"}); err != nil {
+			return err
+		}
+		accounts := Use[Account, string](tx)
+		if _, err := accounts.Create(ctx, Account{UUID: "1234"}); err != nil {
+			return err
+		}
+		_, err := accounts.Create(ctx, Account{UUID: "1234"})
+		return err
+	})
+	s.Assert().ErrorIs(err, ErrConflict)
+	s.Assert().False(called)
+	s.Assert().Zero(s.count(&Test{}))
+	s.Assert().Zero(s.count(&Account{}))
+}
+
+func (s *UnitOfWorkTestSuite) TestDo_Error() {
+	errFailed := errors.New("failed")
+	err := s.uow.Do(context.Background(), func(ctx context.Context, tx *Tx) error {
+		if _, err := Use[Test, uint](tx).CreateBulk(ctx, []Test{{FirstName: "Marcos"}, {FirstName: "Andres"}}); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	s.Assert().ErrorIs(err, errFailed)
+	s.Assert().Zero(s.count(&Test{}))
+}
+
+func (s *UnitOfWorkTestSuite) TestDo_Panic() {
+	s.Assert().Panics(func() {
+		_ = s.uow.Do(context.Background(), func(ctx context.Context, tx *Tx) error {
+			if _, err := Use[Test, uint](tx).Create(ctx, Test{FirstName: "Marcos"}); err != nil {
+				return err
+			}
+			panic("failed")
+		})
+	})
+	s.Assert().Zero(s.count(&Test{}))
+}
+
+func (s *UnitOfWorkTestSuite) TestDo_Nested() {
+	var calls []string
+	err := s.uow.Do(context.Background(), func(ctx context.Context, tx *Tx) error {
+		if _, err := Use[Test, uint](tx).Create(ctx, Test{FirstName: "Marcos"}); err != nil {
+			return err
+		}
+		err := NewUnitOfWork(tx.db).Do(ctx, func(ctx context.Context, nested *Tx) error {
+			nested.AfterCommit(func(ctx context.Context) {
+				calls = append(calls, "nested")
+			})
+			if _, err := Use[Test, uint](nested).Create(ctx, Test{FirstName: "Andres"}); err != nil {
+				return err
+			}
+			return errors.New("failed")
+		})
+		s.Assert().Error(err)
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Assert().Empty(calls)
+	s.Assert().Equal(int64(1), s.count(&Test{}))
+}
+
+func (s *UnitOfWorkTestSuite) TestDo_NestedCommit() {
+	var calls []string
+	errFailed := errors.New("failed")
+	err := s.uow.Do(context.Background(), func(ctx context.Context, tx *Tx) error {
+		tx.AfterCommit(func(ctx context.Context) {
+			calls = append(calls, "outer")
+		})
+		err := NewUnitOfWork(tx.db).Do(ctx, func(ctx context.Context, nested *Tx) error {
+			nested.AfterCommit(func(ctx context.Context) {
+				calls = append(calls, "nested")
+			})
+			_, err := Use[Test, uint](nested).Create(ctx, Test{FirstName: "Andres"})
+			return err
+		})
+		s.Require().NoError(err)
+		s.Assert().Empty(calls)
+		return errFailed
+	})
+	s.Assert().ErrorIs(err, errFailed)
+	s.Assert().Empty(calls)
+	s.Assert().Zero(s.count(&Test{}))
+
+	err = s.uow.Do(context.Background(), func(ctx context.Context, tx *Tx) error {
+		tx.AfterCommit(func(ctx context.Context) {
+			calls = append(calls, "outer")
+		})
+		return NewUnitOfWork(tx.db).Do(ctx, func(ctx context.Context, nested *Tx) error {
+			nested.AfterCommit(func(ctx context.Context) {
+				calls = append(calls, "nested")
+			})
+			return nil
+		})
+	})
+	s.Require().NoError(err)
+	s.Assert().Equal([]string{"outer", "nested"}, calls)
+}
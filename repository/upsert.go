@@ -0,0 +1,53 @@
+package repository
+
+// upsertOptions contains the configuration of an upsert operation.
+type upsertOptions struct {
+	// conflict contains the fields identifying conflicting entities. If empty, the primary key is used.
+	conflict []string
+	// update contains the fields updated on conflict. If empty, every field but the primary key and the creation
+	// timestamp is updated.
+	update []string
+	// nothing determines whether conflicting entities are left untouched.
+	nothing bool
+}
+
+// UpsertOption configures Repository.Upsert and Repository.UpsertBulk.
+type UpsertOption func(o *upsertOptions)
+
+// OnConflict sets the fields identifying conflicting entities, which must be covered by a primary key or a unique
+// index. It accepts either struct field names or column names, and defaults to the primary key.
+//
+// MySQL detects conflicts on every unique index of the table, so the fields are only used to look up the
+// persisted entities.
+func OnConflict(fields ...string) UpsertOption {
+	return func(o *upsertOptions) {
+		o.conflict = fields
+	}
+}
+
+// DoUpdate sets the fields updated when an entity conflicts with an existing one. It accepts either struct field
+// names or column names, and defaults to every field but the primary key and the creation timestamp.
+func DoUpdate(fields ...string) UpsertOption {
+	return func(o *upsertOptions) {
+		o.update = fields
+		o.nothing = false
+	}
+}
+
+// DoNothing leaves existing entities untouched when they conflict with the upserted ones, which then only creates
+// the missing entities.
+func DoNothing() UpsertOption {
+	return func(o *upsertOptions) {
+		o.update = nil
+		o.nothing = true
+	}
+}
+
+// newUpsertOptions returns the default upsert options with the given opts applied.
+func newUpsertOptions(opts []UpsertOption) upsertOptions {
+	var o upsertOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
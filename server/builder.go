@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"net/http"
@@ -16,6 +17,8 @@ type Builder struct {
 	router         chi.Router
 	controllers    []Controller
 	middlewares    []func(handler http.Handler) http.Handler
+	shutdownHooks  []func(ctx context.Context) error
+	metrics        http.Handler
 	signals        []os.Signal
 	signalsChannel chan os.Signal
 	port           uint16
@@ -29,11 +32,13 @@ func (builder *Builder) Build() *Server {
 	builder.router = chi.NewRouter()
 	builder.buildMiddlewares()
 	builder.buildRoutes()
+	builder.buildMetrics()
 	builder.buildSignals()
 
 	return &Server{
-		http: builder.buildHTTP(),
-		sigs: builder.signalsChannel,
+		http:  builder.buildHTTP(),
+		sigs:  builder.signalsChannel,
+		hooks: builder.shutdownHooks,
 	}
 }
 
@@ -62,6 +67,14 @@ func (builder *Builder) buildRoutes() {
 	}
 }
 
+// buildMetrics is an internal method that exposes the metrics handler, if any, before the final build call.
+func (builder *Builder) buildMetrics() {
+	if builder.metrics == nil {
+		return
+	}
+	builder.router.Handle("/metrics", builder.metrics)
+}
+
 // buildMiddlewares is an internal method that processes all the middlewares before the final build call.
 func (builder *Builder) buildMiddlewares() {
 	if len(builder.middlewares) == 0 {
@@ -95,6 +108,22 @@ func (builder *Builder) Middleware(mw func(handler http.Handler) http.Handler) *
 	return builder
 }
 
+// Metrics allows the developer to expose metrics in the /metrics route, such as the ones collected by a
+// telemetry.Registry.
+// This method allows a single call.
+func (builder *Builder) Metrics(handler http.Handler) *Builder {
+	builder.metrics = handler
+	return builder
+}
+
+// OnShutdown allows the developer to specify a function that will be called once the HTTP server has been shut down.
+// It's used for releasing resources such as database connections. Hooks are called in the same order they were added.
+// This method allows multiple calls.
+func (builder *Builder) OnShutdown(hook func(ctx context.Context) error) *Builder {
+	builder.shutdownHooks = append(builder.shutdownHooks, hook)
+	return builder
+}
+
 // Signal allows the developer to specify an OS signal that will shut down the server once it listens to incoming
 // HTTP requests. It's used for gracefully shutting down the final web server.
 // This method allows multiple calls.
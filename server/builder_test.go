@@ -4,6 +4,7 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
 	"syscall"
 	"testing"
 	"time"
@@ -103,3 +104,16 @@ func TestServerBuilder_WithSignal(t *testing.T) {
 	assert.NotEmpty(t, builder.signals)
 	assert.Len(t, builder.signals, 3)
 }
+
+func TestServerBuilder_WithMetrics(t *testing.T) {
+	var builder Builder
+
+	srv := builder.Metrics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("metrics"))
+	})).Build()
+
+	rec := httptest.NewRecorder()
+	srv.http.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "metrics", rec.Body.String())
+}
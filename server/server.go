@@ -31,6 +31,8 @@ type Server struct {
 	http *http.Server
 	// sigs contains the OS signals used to Shutdown the Server.
 	sigs chan os.Signal
+	// hooks contains the functions called after the HTTP server has been shut down.
+	hooks []func(ctx context.Context) error
 }
 
 // ListenAndServe listens for incoming HTTP requests until an error occurs.
@@ -57,12 +59,18 @@ func (s *Server) ListenAndServe() error {
 }
 
 // Shutdown attempts to shut down the current server until a timeout of a minute occurs.
-// Calling Shutdown allows the web server to process pending HTTP requests.
+// Calling Shutdown allows the web server to process pending HTTP requests. Once the HTTP server is shut down,
+// the shutdown hooks are called in order.
 func (s *Server) Shutdown() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	if err := s.http.Shutdown(ctx); err != nil {
 		log.Println("Failed to shutdown HTTP server:", err)
 	}
+	for _, hook := range s.hooks {
+		if err := hook(ctx); err != nil {
+			log.Println("Failed to run shutdown hook:", err)
+		}
+	}
 	cancel()
 }
 
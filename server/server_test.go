@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"sync"
 	"testing"
@@ -48,3 +50,21 @@ func (t *testSignal) String() string {
 }
 
 func (t *testSignal) Signal() {}
+
+func TestServer_ShutdownHooks(t *testing.T) {
+	var builder Builder
+	var calls []int
+	srv := builder.
+		OnShutdown(func(ctx context.Context) error {
+			calls = append(calls, 1)
+			return errors.New("failed")
+		}).
+		OnShutdown(func(ctx context.Context) error {
+			calls = append(calls, 2)
+			return nil
+		}).
+		Build()
+
+	srv.Shutdown()
+	assert.Equal(t, []int{1, 2}, calls)
+}
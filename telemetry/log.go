@@ -0,0 +1,98 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger writes structured logs, where every entry contains a message and a set of alternating keys and values.
+type Logger interface {
+	// Debug logs a message useful when troubleshooting an application.
+	Debug(msg string, keyvals ...interface{})
+}
+
+// LogfmtLogger implements Logger writing entries to an io.Writer using the logfmt format, one per line:
+//
+//	time=2023-04-01T10:00:00Z level=debug msg="repository call" method=Get
+//
+// It's safe for concurrent use.
+type LogfmtLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// Debug writes an entry with the debug level.
+func (l *LogfmtLogger) Debug(msg string, keyvals ...interface{}) {
+	l.log("debug", msg, keyvals)
+}
+
+// log writes an entry with the given level. A missing value is written as an empty string.
+func (l *LogfmtLogger) log(level, msg string, keyvals []interface{}) {
+	var b strings.Builder
+	b.WriteString("time=")
+	b.WriteString(l.now().UTC().Format(time.RFC3339Nano))
+	b.WriteString(" level=")
+	b.WriteString(level)
+	b.WriteString(" msg=")
+	b.WriteString(formatLogValue(msg))
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = ""
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		b.WriteByte(' ')
+		b.WriteString(formatLogKey(fmt.Sprint(keyvals[i])))
+		b.WriteByte('=')
+		b.WriteString(formatLogValue(value))
+	}
+	b.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.w, b.String())
+}
+
+// formatLogKey returns key without the characters that aren't allowed in logfmt keys.
+func formatLogKey(key string) string {
+	key = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+	if key == "" {
+		return "_"
+	}
+	return key
+}
+
+// formatLogValue returns v formatted as a logfmt value, quoting it if needed.
+func formatLogValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case error:
+		s = v.Error()
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\\") || strings.IndexFunc(s, func(r rune) bool { return r < ' ' }) >= 0 {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+// NewLogfmtLogger initializes a new LogfmtLogger writing to w.
+func NewLogfmtLogger(w io.Writer) *LogfmtLogger {
+	return &LogfmtLogger{
+		w:   w,
+		now: time.Now,
+	}
+}
@@ -0,0 +1,24 @@
+package telemetry
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLogfmtLogger_Debug(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogfmtLogger(&buf)
+	l.now = func() time.Time {
+		return time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+	}
+
+	l.Debug("repository call", "method", "Get", "duration", 1500*time.Millisecond, "error", errors.New("entity not found"), "ids", 2)
+	l.Debug("empty", "value", "", "nil", nil, "bad key", "a=b", "missing")
+
+	assert.Equal(t, `time=2023-04-01T10:00:00Z level=debug msg="repository call" method=Get duration=1.5s error="entity not found" ids=2
+time=2023-04-01T10:00:00Z level=debug msg=empty value="" nil=null bad_key="a=b" missing=""
+`, buf.String())
+}
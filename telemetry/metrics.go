@@ -0,0 +1,289 @@
+// Package telemetry contains the building blocks used to observe applications built with Jaguar. Metrics are
+// collected in a Registry that exposes them using the Prometheus text format, while logs and traces are reported
+// through the Logger and Tracer interfaces.
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets contains the default histogram buckets, measured in seconds. They're suitable for measuring the
+// latency of network requests and database queries.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds a set of metrics and exposes them using the Prometheus text format. A Registry implements
+// http.Handler, allowing it to be served by an HTTP server.
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]*metric
+}
+
+// NewRegistry initializes an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]*metric),
+	}
+}
+
+// Counter returns the counter identified by the given name, registering it if it doesn't exist.
+// It panics if a metric with the same name but a different type or labels was already registered.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{metric: r.register(name, help, "counter", nil, labels)}
+}
+
+// Gauge returns the gauge identified by the given name, registering it if it doesn't exist.
+// It panics if a metric with the same name but a different type or labels was already registered.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{metric: r.register(name, help, "gauge", nil, labels)}
+}
+
+// Histogram returns the histogram identified by the given name, registering it if it doesn't exist. Buckets must
+// be sorted in increasing order, DefaultBuckets is used if no buckets are provided.
+// It panics if a metric with the same name but a different type or labels was already registered.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{metric: r.register(name, help, "histogram", buckets, labels)}
+}
+
+// register returns the metric identified by name, creating it if it doesn't exist.
+func (r *Registry) register(name, help, kind string, buckets []float64, labels []string) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.metrics[name]; ok {
+		if m.kind != kind || strings.Join(m.labels, ",") != strings.Join(labels, ",") {
+			panic(fmt.Sprintf("telemetry: metric %s already registered with a different type or labels", name))
+		}
+		return m
+	}
+	m := &metric{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.metrics[name] = m
+	return m
+}
+
+// WriteText writes every metric to w using the Prometheus text format. Metrics and series are sorted to produce
+// a stable output.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	metrics := make([]*metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mu.RUnlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].name < metrics[j].name
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP writes every metric to the HTTP response using the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := r.WriteText(w); err != nil {
+		http.Error(w, "Failed to write metrics", http.StatusInternalServerError)
+	}
+}
+
+// Counter is a metric that can only increase.
+type Counter struct {
+	metric *metric
+}
+
+// Inc increments the counter identified by the given label values by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter identified by the given label values by v. It panics if v is negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("telemetry: counters cannot decrease")
+	}
+	c.metric.update(labelValues, func(s *series) {
+		s.value += v
+	})
+}
+
+// Gauge is a metric that can arbitrarily go up and down.
+type Gauge struct {
+	metric *metric
+}
+
+// Set sets the gauge identified by the given label values to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.metric.update(labelValues, func(s *series) {
+		s.value = v
+	})
+}
+
+// Add adds v to the gauge identified by the given label values. The v parameter can be negative.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.metric.update(labelValues, func(s *series) {
+		s.value += v
+	})
+}
+
+// Histogram is a metric that samples observations in configurable buckets.
+type Histogram struct {
+	metric *metric
+}
+
+// Observe adds v to the histogram identified by the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.metric.update(labelValues, func(s *series) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(h.metric.buckets))
+		}
+		for i, upper := range h.metric.buckets {
+			if v <= upper {
+				s.counts[i]++
+			}
+		}
+		s.count++
+		s.value += v
+	})
+}
+
+// metric contains every series of a metric identified by its name.
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series contains the values of a metric for a certain set of label values. The value field contains the sum of
+// observations for histograms.
+type series struct {
+	labelValues []string
+	value       float64
+	count       uint64
+	counts      []uint64
+}
+
+// update calls fn with the series identified by the given label values, creating it if it doesn't exist.
+func (m *metric) update(labelValues []string, fn func(s *series)) {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("telemetry: metric %s expects %d label values, got %d", m.name, len(m.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		m.series[key] = s
+	}
+	fn(s)
+}
+
+// write writes the current metric to w using the Prometheus text format.
+func (m *metric) write(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.series) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+	for _, key := range keys {
+		s := m.series[key]
+		if m.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, formatLabels(m.labels, s.labelValues), formatFloat(s.value))
+			continue
+		}
+		for i, upper := range m.buckets {
+			var count uint64
+			if s.counts != nil {
+				count = s.counts[i]
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatBucketLabels(m.labels, s.labelValues, formatFloat(upper)), count)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, formatBucketLabels(m.labels, s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, formatLabels(m.labels, s.labelValues), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, formatLabels(m.labels, s.labelValues), s.count)
+	}
+}
+
+// formatLabels returns the given labels in the Prometheus text format: {name="value",...}.
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatBucketLabels returns the given labels in the Prometheus text format, including the "le" label used to
+// identify histogram buckets.
+func formatBucketLabels(names, values []string, upper string) string {
+	n := append(append(make([]string, 0, len(names)+1), names...), "le")
+	v := append(append(make([]string, 0, len(values)+1), values...), upper)
+	return formatLabels(n, v)
+}
+
+// formatFloat formats the given value using the Prometheus text format.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// escapeHelp escapes backslashes and line feeds from metric descriptions.
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	reg := NewRegistry()
+	reg.Counter("requests_total", "Total requests.", "method").Inc("GET")
+	reg.Counter("requests_total", "Total requests.", "method").Add(2, "POST")
+	reg.Gauge("temperature", "Current \"temperature\".").Set(21.5)
+	h := reg.Histogram("latency_seconds", "Request latency.", []float64{0.1, 1}, "path")
+	h.Observe(0.05, "/users")
+	h.Observe(0.5, "/users")
+
+	var buf bytes.Buffer
+	assert.NoError(t, reg.WriteText(&buf))
+	assert.Equal(t, `# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{path="/users",le="0.1"} 1
+latency_seconds_bucket{path="/users",le="1"} 2
+latency_seconds_bucket{path="/users",le="+Inf"} 2
+latency_seconds_sum{path="/users"} 0.55
+latency_seconds_count{path="/users"} 2
+# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="GET"} 1
+requests_total{method="POST"} 2
+# HELP temperature Current "temperature".
+# TYPE temperature gauge
+temperature 21.5
+`, buf.String())
+}
+
+func TestRegistry_Conflicts(t *testing.T) {
+	reg := NewRegistry()
+	reg.Counter("requests_total", "Total requests.", "method")
+
+	assert.Panics(t, func() {
+		reg.Gauge("requests_total", "Total requests.", "method")
+	})
+	assert.Panics(t, func() {
+		reg.Counter("requests_total", "Total requests.", "path")
+	})
+	assert.Panics(t, func() {
+		reg.Counter("requests_total", "Total requests.", "method").Inc()
+	})
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	reg := NewRegistry()
+	reg.Gauge("up", "Whether the service is up.", "label").Set(1, "a\"b")
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, rec.Body.String(), `up{label="a\"b"} 1`)
+}
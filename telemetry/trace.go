@@ -0,0 +1,23 @@
+package telemetry
+
+import (
+	"context"
+)
+
+// Tracer starts spans measuring operations, allowing Jaguar to report traces to any tracing system, such as
+// OpenTelemetry, by implementing this interface.
+type Tracer interface {
+	// Start starts a span with the given name, as a child of the span held by ctx if any. The returned context
+	// holds the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an operation measured by a Tracer.
+type Span interface {
+	// SetAttribute sets an attribute describing the operation.
+	SetAttribute(key string, value interface{})
+	// RecordError marks the operation as failed with the given error.
+	RecordError(err error)
+	// End completes the operation.
+	End()
+}